@@ -29,6 +29,8 @@ var CheckMemCmd = &cobra.Command{
 			log.Printf("SReclaimable: %d MiB", info.SReclaimable/1024/1024)
 			log.Printf("Zswap:        %d MiB", info.Zswap/1024/1024)
 			log.Printf("Zswapped:     %d MiB", info.Zswapped/1024/1024)
+			log.Printf("Dirty:        %d MiB", info.Dirty/1024/1024)
+			log.Printf("HugePages:    %d/%d free (%d KiB each)", info.HugePagesFree, info.HugePagesTotal, info.HugePageSize/1024)
 			log.Println("---------------------")
 		}
 
@@ -45,7 +47,7 @@ var CheckMemCmd = &cobra.Command{
 		printRamInfo(monitoring.CallFree())
 
 		log.Println("--- Current Configured ---")
-		printRamInfo(monitoring.Ram())
+		printRamInfo(monitoring.Ram(monitoring.ReadCgroup()))
 	},
 }
 
@@ -14,21 +14,42 @@ type Config struct {
 	InfoReportInterval  int     `json:"info_report_interval" env:"AGENT_INFO_REPORT_INTERVAL"`     // 基础信息上报间隔，单位分钟
 	IncludeNics         string  `json:"include_nics" env:"AGENT_INCLUDE_NICS"`                     // 仅统计网卡，逗号分隔的网卡名称列表，支持通配符
 	ExcludeNics         string  `json:"exclude_nics" env:"AGENT_EXCLUDE_NICS"`                     // 统计时排除的网卡，逗号分隔的网卡名称列表，支持通配符
+	NicDetails          bool    `json:"nic_details" env:"AGENT_NIC_DETAILS"`                       // 上报中包含每个网卡的流量统计
 	IncludeMountpoints  string  `json:"include_mountpoints" env:"AGENT_INCLUDE_MOUNTPOINTS"`       // 磁盘统计的包含挂载点列表，使用分号分隔
+	DiskPerMount        bool    `json:"disk_per_mount" env:"AGENT_DISK_PER_MOUNT"`                 // 上报中包含每个挂载点的使用情况与 inode 统计
 	MonthRotate         int     `json:"month_rotate" env:"AGENT_MONTH_ROTATE"`                     // 流量统计的月份重置日期（0表示禁用）
 	MemoryIncludeCache  bool    `json:"memory_include_cache" env:"AGENT_MEMORY_INCLUDE_CACHE"`     // 包括缓存/缓冲区的内存使用情况
 	MemoryReportRawUsed bool    `json:"memory_report_raw_used" env:"AGENT_MEMORY_REPORT_RAW_USED"` // 使用原始内存使用情况报告
+	MemoryDetail        bool    `json:"memory_detail" env:"AGENT_MEMORY_DETAIL"`                   // 上报中包含内存构成明细
 	CustomDNS           string  `json:"custom_dns" env:"AGENT_CUSTOM_DNS"`                         // 使用的自定义DNS服务器
 	EnableGPU           bool    `json:"enable_gpu" env:"AGENT_ENABLE_GPU"`                         // 启用详细GPU监控
+	CpuPerCore          bool    `json:"cpu_per_core" env:"AGENT_CPU_PER_CORE"`                     // 上报中包含每个逻辑核心的使用率
+	TopProcesses        int     `json:"top_processes" env:"AGENT_TOP_PROCESSES"`                   // 上报中包含 CPU 与内存占用最高的进程数量（0表示不上报）
 	ShowWarning         bool    `json:"show_warning" env:"AGENT_SHOW_WARNING"`                     // Windows 上显示安全警告，作为子进程运行一次
 	CustomIpv4          string  `json:"custom_ipv4" env:"AGENT_CUSTOM_IPV4"`                       // 自定义 IPv4 地址
 	CustomIpv6          string  `json:"custom_ipv6" env:"AGENT_CUSTOM_IPV6"`                       // 自定义 IPv6 地址
 	GetIpAddrFromNic    bool    `json:"get_ip_addr_from_nic" env:"AGENT_GET_IP_ADDR_FROM_NIC"`     // 从网卡获取IP地址
 	HostProc            string  `json:"host_proc" env:"HOST_PROC"`                                 // 容器环境下宿主机/proc目录的挂载点，用于监控宿主机进程
+	HostSys             string  `json:"host_sys" env:"HOST_SYS"`                                   // 容器环境下宿主机/sys目录的挂载点，用于读取硬件传感器
 	ConfigFile          string  `json:"config_file" env:"AGENT_CONFIG_FILE"`                       // JSON配置文件路径
 	ProtocolVersion     int     `json:"protocol_version" env:"AGENT_PROTOCOL_VERSION"`             // 上报协议版本，默认2
 	DisableCompression  bool    `json:"disable_compression" env:"AGENT_DISABLE_COMPRESSION"`       // 禁用v2传输压缩
 	PreferIPVersion     string  `json:"prefer_ip_version" env:"AGENT_PREFER_IP_VERSION"`           // 面板连接优先使用的 IP 版本：4 或 6
+	RecordTerminal      bool    `json:"record_terminal" env:"AGENT_RECORD_TERMINAL"`               // 以 asciicast v2 格式录制 Web SSH 会话
+	RecordTerminalInput bool    `json:"record_terminal_input" env:"AGENT_RECORD_TERMINAL_INPUT"`   // 录制中包含用户输入
+	RecordDir           string  `json:"record_dir" env:"AGENT_RECORD_DIR"`                         // 录制文件保存目录
+	RecordMaxFiles      int     `json:"record_max_files" env:"AGENT_RECORD_MAX_FILES"`             // 最多保留的录制文件数（0表示不限制）
+	RecordMaxAgeDays    int     `json:"record_max_age_days" env:"AGENT_RECORD_MAX_AGE_DAYS"`       // 录制文件保留天数（0表示不限制）
+	RecordUpload        bool    `json:"record_upload" env:"AGENT_RECORD_UPLOAD"`                   // 会话结束后将录制文件上传到面板
+	TerminalMaxSessions int     `json:"terminal_max_sessions" env:"AGENT_TERMINAL_MAX_SESSIONS"`   // 最大并发终端会话数（0表示不限制）
+	TerminalIdleTimeout int     `json:"terminal_idle_timeout" env:"AGENT_TERMINAL_IDLE_TIMEOUT"`   // 终端空闲超时，单位分钟（0表示不限制）
+	TerminalMaxLifetime int     `json:"terminal_max_lifetime" env:"AGENT_TERMINAL_MAX_LIFETIME"`   // 终端会话最长存活时间，单位分钟（0表示不限制）
+	TerminalResumeGrace int     `json:"terminal_resume_grace" env:"AGENT_TERMINAL_RESUME_GRACE"`   // 可恢复终端会话断开后保留的时间，单位秒（0表示断开即关闭）
+	TerminalUsers       string  `json:"terminal_users" env:"AGENT_TERMINAL_USERS"`                 // 终端请求可切换到的用户，逗号分隔（为空表示只能使用 Agent 自身的用户）
+	TerminalShells      string  `json:"terminal_shells" env:"AGENT_TERMINAL_SHELLS"`               // 终端请求可指定的 shell，逗号分隔的完整路径或程序名（为空表示不允许指定）
+	TunnelAllow         string  `json:"tunnel_allow" env:"AGENT_TUNNEL_ALLOW"`                     // 允许端口转发的目标，逗号分隔的 host:port，端口可用 * 表示任意（为空表示禁用）
+	TunnelMax           int     `json:"tunnel_max" env:"AGENT_TUNNEL_MAX"`                         // 最大并发端口转发数（0表示不限制）
+	TunnelIdleTimeout   int     `json:"tunnel_idle_timeout" env:"AGENT_TUNNEL_IDLE_TIMEOUT"`       // 端口转发空闲超时，单位分钟（0表示不限制）
 
 }
 
@@ -96,6 +96,9 @@ var RootCmd = &cobra.Command{
 			log.Printf("Using system default DNS resolver")
 		}
 
+		// 检测 ICMP 探测可用的方式（原始套接字 / 数据报 / TCP 近似）
+		server.DetectICMPMode()
+
 		// Auto discovery
 		if flags.AutoDiscoveryKey != "" {
 			err := handleAutoDiscovery()
@@ -171,12 +174,17 @@ func init() {
 	RootCmd.PersistentFlags().IntVar(&flags.InfoReportInterval, "info-report-interval", 5, "Interval in minutes for reporting basic info")
 	RootCmd.PersistentFlags().StringVar(&flags.IncludeNics, "include-nics", "", "Comma-separated list of network interfaces to include")
 	RootCmd.PersistentFlags().StringVar(&flags.ExcludeNics, "exclude-nics", "", "Comma-separated list of network interfaces to exclude")
+	RootCmd.PersistentFlags().BoolVar(&flags.NicDetails, "nic-details", false, "Include per-interface network statistics in reports")
 	RootCmd.PersistentFlags().StringVar(&flags.IncludeMountpoints, "include-mountpoint", "", "Semicolon-separated list of mount points to include for disk statistics")
+	RootCmd.PersistentFlags().BoolVar(&flags.DiskPerMount, "disk-per-mount", false, "Include per-mountpoint disk usage and inode statistics in reports")
 	RootCmd.PersistentFlags().IntVar(&flags.MonthRotate, "month-rotate", 0, "Month reset for network statistics (0 to disable)")
 	RootCmd.PersistentFlags().BoolVar(&flags.MemoryIncludeCache, "memory-include-cache", false, "Include cache/buffer in memory usage")
 	RootCmd.PersistentFlags().BoolVar(&flags.MemoryReportRawUsed, "memory-exclude-bcf", false, "Use \"raminfo.Used = v.Total - v.Free - v.Buffers - v.Cached\" calculation for memory usage")
+	RootCmd.PersistentFlags().BoolVar(&flags.MemoryDetail, "memory-detail", false, "Include a memory breakdown (available, cached, buffers, hugepages, zswap...) in reports")
 	RootCmd.PersistentFlags().StringVar(&flags.CustomDNS, "custom-dns", "", "Custom DNS server to use (e.g. 8.8.8.8, 114.114.114.114). By default, the program uses the system DNS resolver.")
 	RootCmd.PersistentFlags().BoolVar(&flags.EnableGPU, "gpu", false, "Enable detailed GPU monitoring (usage, memory, multi-GPU support)")
+	RootCmd.PersistentFlags().BoolVar(&flags.CpuPerCore, "cpu-per-core", false, "Include per-core CPU usage in reports")
+	RootCmd.PersistentFlags().IntVar(&flags.TopProcesses, "top-processes", 0, "Number of top processes by CPU and memory to include in reports (0 to disable)")
 	RootCmd.PersistentFlags().BoolVar(&flags.ShowWarning, "show-warning", false, "Show security warning on Windows, run once as a subprocess")
 	RootCmd.PersistentFlags().StringVar(&flags.CustomIpv4, "custom-ipv4", "", "Custom IPv4 address to use")
 	RootCmd.PersistentFlags().StringVar(&flags.CustomIpv6, "custom-ipv6", "", "Custom IPv6 address to use")
@@ -185,6 +193,21 @@ func init() {
 	RootCmd.PersistentFlags().IntVar(&flags.ProtocolVersion, "protocol-version", 2, "Report protocol version (1 or 2)")
 	RootCmd.PersistentFlags().BoolVar(&flags.DisableCompression, "disable-compression", false, "Disable v2 gzip/permessage-deflate compression")
 	RootCmd.PersistentFlags().StringVar(&flags.PreferIPVersion, "prefer-ip-version", "", "Prefer IP version for dashboard connections: 4 or 6")
+	RootCmd.PersistentFlags().BoolVar(&flags.RecordTerminal, "record-terminal", false, "Record web SSH sessions in asciicast v2 format")
+	RootCmd.PersistentFlags().BoolVar(&flags.RecordTerminalInput, "record-terminal-input", false, "Include user input in terminal recordings")
+	RootCmd.PersistentFlags().StringVar(&flags.RecordDir, "record-dir", "./recordings", "Directory to store terminal recordings")
+	RootCmd.PersistentFlags().IntVar(&flags.RecordMaxFiles, "record-max-files", 100, "Maximum number of terminal recordings to keep (0 for unlimited)")
+	RootCmd.PersistentFlags().IntVar(&flags.RecordMaxAgeDays, "record-max-age-days", 30, "Days to keep terminal recordings (0 for unlimited)")
+	RootCmd.PersistentFlags().BoolVar(&flags.RecordUpload, "record-upload", false, "Upload finished terminal recordings to the dashboard")
+	RootCmd.PersistentFlags().IntVar(&flags.TerminalMaxSessions, "terminal-max-sessions", 10, "Maximum number of concurrent terminal sessions (0 for unlimited)")
+	RootCmd.PersistentFlags().IntVar(&flags.TerminalIdleTimeout, "terminal-idle-timeout", 30, "Close terminal sessions idle for this many minutes (0 to disable)")
+	RootCmd.PersistentFlags().IntVar(&flags.TerminalMaxLifetime, "terminal-max-lifetime", 0, "Close terminal sessions after this many minutes (0 to disable)")
+	RootCmd.PersistentFlags().IntVar(&flags.TerminalResumeGrace, "terminal-resume-grace", 300, "Keep resumable terminal sessions alive for this many seconds after the connection drops (0 to disable)")
+	RootCmd.PersistentFlags().StringVar(&flags.TerminalUsers, "terminal-users", "", "Comma-separated list of users terminal requests may switch to (requires running as root)")
+	RootCmd.PersistentFlags().StringVar(&flags.TerminalShells, "terminal-shells", "", "Comma-separated list of shells (full paths or names) terminal requests may choose")
+	RootCmd.PersistentFlags().StringVar(&flags.TunnelAllow, "tunnel-allow", "", "Comma-separated host:port destinations allowed for port forwarding, port may be * (empty disables tunnels)")
+	RootCmd.PersistentFlags().IntVar(&flags.TunnelMax, "tunnel-max", 4, "Maximum number of concurrent port forwarding tunnels (0 for unlimited)")
+	RootCmd.PersistentFlags().IntVar(&flags.TunnelIdleTimeout, "tunnel-idle-timeout", 10, "Close port forwarding tunnels idle for this many minutes (0 to disable)")
 	RootCmd.PersistentFlags().ParseErrorsWhitelist.UnknownFlags = true
 }
 
@@ -141,7 +141,7 @@ func buildTransportWithPreference(timeout time.Duration, tlsConfig *tls.Config,
 			if err != nil {
 				return nil, err
 			}
-			sortIPsByPreference(ips, preferIPVersion)
+			SortIPsByPreference(ips, preferIPVersion)
 			for _, ip := range ips {
 				dialer := &net.Dialer{
 					Timeout:   timeout,
@@ -242,7 +242,7 @@ func GetDialContextWithPreference(timeout time.Duration, preferIPVersion string)
 			return nil, err
 		}
 
-		sortIPsByPreference(ips, preferIPVersion)
+		SortIPsByPreference(ips, preferIPVersion)
 
 		// 逐个 IP 尝试连接
 		for _, ip := range ips {
@@ -267,7 +267,9 @@ func normalizeIPVersionPreference(preferIPVersion string) string {
 	return ""
 }
 
-func sortIPsByPreference(ips []string, preferIPVersion string) {
+// SortIPsByPreference 按 IP 版本偏好原地排序地址列表。
+// preferIPVersion 为 "4" 或 "6" 时对应地址排在前面；为空时根据本机是否具备 IPv4 自动选择。
+func SortIPsByPreference(ips []string, preferIPVersion string) {
 	preferIPVersion = normalizeIPVersionPreference(preferIPVersion)
 	if preferIPVersion == "" {
 		// 根据本机是否具备 IPv4 动态排序
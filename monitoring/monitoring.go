@@ -14,19 +14,29 @@ var flags = pkg_flags.GlobalConfig
 type report struct {
 	CPU         cpuReport         `json:"cpu"`
 	Ram         usageReport       `json:"ram"`
+	Memory      *unit.MemDetail   `json:"memory,omitempty"`
 	Swap        usageReport       `json:"swap"`
 	Load        loadReport        `json:"load"`
+	Pressure    unit.PressureInfo `json:"pressure"`
+	Cgroup      *unit.CgroupInfo  `json:"cgroup,omitempty"` // 在容器中运行时 cpu 与 ram 基于 cgroup 限制
 	Disk        usageReport       `json:"disk"`
+	DiskIO      []unit.DiskIOInfo `json:"disk_io,omitempty"`
+	Mountpoints []unit.MountInfo  `json:"mountpoints,omitempty"`
 	Network     networkReport     `json:"network"`
+	Interfaces  []unit.NicInfo    `json:"interfaces,omitempty"`
 	Connections connectionsReport `json:"connections"`
 	GPU         interface{}       `json:"gpu,omitempty"`
+	Sensors     *unit.SensorsInfo `json:"sensors,omitempty"`
 	Uptime      uint64            `json:"uptime"`
 	Process     int               `json:"process"`
+	TopProcess  *unit.ProcessTop  `json:"top_processes,omitempty"`
 	Message     string            `json:"message"`
 }
 
 type cpuReport struct {
-	Usage float64 `json:"usage"`
+	Usage   float64        `json:"usage"`
+	Times   *unit.CpuTimes `json:"times,omitempty"`    // 各类 CPU 时间占比，采集失败时省略
+	PerCore []float64      `json:"per_core,omitempty"` // 每个逻辑核心的使用率，需启用 --cpu-per-core
 }
 
 type usageReport struct {
@@ -48,8 +58,11 @@ type networkReport struct {
 }
 
 type connectionsReport struct {
-	TCP int `json:"tcp"`
-	UDP int `json:"udp"`
+	TCP         int                       `json:"tcp"`
+	UDP         int                       `json:"udp"`
+	TCPStates   map[string]map[string]int `json:"tcp_states,omitempty"` // 按地址族 (ipv4/ipv6) 统计的 TCP 状态
+	Listen      int                       `json:"listen"`
+	RemotePeers int                       `json:"remote_peers"`
 }
 
 type gpuModelsReport struct {
@@ -73,36 +86,79 @@ type gpuDeviceReport struct {
 func GenerateReport() []byte {
 	message := ""
 	data := report{}
+	// cgroup 只读取一次，cpu、ram、memory 与 cgroup 字段基于同一份数据
+	cg := unit.ReadCgroup()
 
-	cpu := unit.Cpu()
+	cpu := unit.Cpu(cg)
 	cpuUsage := cpu.CPUUsage
 	if cpuUsage <= 0.001 {
 		cpuUsage = 0.001
 	}
 	data.CPU = cpuReport{Usage: cpuUsage}
+	cpuTimes, err := unit.CpuTimesDetail(flags.CpuPerCore)
+	if err != nil {
+		message += fmt.Sprintf("failed to get cpu times: %v\n", err)
+	} else {
+		data.CPU.Times = &cpuTimes.Total
+		data.CPU.PerCore = cpuTimes.PerCore
+	}
 
-	ram := unit.Ram()
+	ram := unit.Ram(cg)
 	data.Ram = usageReport{Total: ram.Total, Used: ram.Used}
+	if flags.MemoryDetail {
+		detail := unit.MemoryDetail(ram.Mode, cg)
+		data.Memory = &detail
+	}
 
 	swap := unit.Swap()
 	data.Swap = usageReport{Total: swap.Total, Used: swap.Used}
 	load := unit.Load()
 	data.Load = loadReport{Load1: load.Load1, Load5: load.Load5, Load15: load.Load15}
+	data.Pressure = unit.Pressure()
+	if cg != nil {
+		info := cg.Info()
+		data.Cgroup = &info
+	}
 
 	disk := unit.Disk()
 	data.Disk = usageReport{Total: disk.Total, Used: disk.Used}
+	diskIO, err := unit.DiskIO()
+	if err != nil {
+		message += fmt.Sprintf("failed to get disk io: %v\n", err)
+	}
+	data.DiskIO = diskIO
+	if flags.DiskPerMount {
+		mountpoints, err := unit.Mountpoints()
+		if err != nil {
+			message += fmt.Sprintf("failed to get mountpoints: %v\n", err)
+		}
+		data.Mountpoints = mountpoints
+	}
 
 	totalUp, totalDown, networkUp, networkDown, err := unit.NetworkSpeed()
 	if err != nil {
 		message += fmt.Sprintf("failed to get network speed: %v\n", err)
 	}
 	data.Network = networkReport{Up: networkUp, Down: networkDown, TotalUp: totalUp, TotalDown: totalDown}
+	if flags.NicDetails {
+		nics, err := unit.Nics()
+		if err != nil {
+			message += fmt.Sprintf("failed to get interface stats: %v\n", err)
+		}
+		data.Interfaces = nics
+	}
 
-	tcpCount, udpCount, err := unit.ConnectionsCount()
+	conns, err := unit.Connections()
 	if err != nil {
 		message += fmt.Sprintf("failed to get connections: %v\n", err)
 	}
-	data.Connections = connectionsReport{TCP: tcpCount, UDP: udpCount}
+	data.Connections = connectionsReport{
+		TCP:         conns.TCP,
+		UDP:         conns.UDP,
+		TCPStates:   conns.TCPStates,
+		Listen:      conns.Listen,
+		RemotePeers: conns.RemotePeers,
+	}
 
 	uptime, err := unit.Uptime()
 	if err != nil {
@@ -111,6 +167,18 @@ func GenerateReport() []byte {
 	data.Uptime = uptime
 
 	data.Process = unit.ProcessCount()
+	if flags.TopProcesses > 0 {
+		top, err := unit.TopProcesses(flags.TopProcesses)
+		if err != nil {
+			message += fmt.Sprintf("failed to get top processes: %v\n", err)
+		} else {
+			data.TopProcess = &top
+		}
+	}
+
+	if sensors := unit.Sensors(); !sensors.Empty() {
+		data.Sensors = &sensors
+	}
 
 	// GPU监控 - 根据标志决定详细程度
 	if flags.EnableGPU {
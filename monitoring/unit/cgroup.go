@@ -0,0 +1,163 @@
+package monitoring
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// CgroupInfo 容器内 cgroup 限制下的资源情况
+type CgroupInfo struct {
+	Version     int     `json:"version"`      // cgroup 版本，1 或 2
+	CPUQuota    float64 `json:"cpu_quota"`    // 可用的 CPU 核数，0 表示不限制
+	CPUUsage    float64 `json:"cpu_usage"`    // 相对于配额（不限制时相对于全部可用核心）的使用率 (0-100)
+	MemoryLimit uint64  `json:"memory_limit"` // 内存上限，0 表示不限制
+	MemoryUsed  uint64  `json:"memory_used"`  // 已用内存，默认不含可回收的文件缓存
+}
+
+// cgroupStats 一次读取到的 cgroup 原始数据
+type cgroupStats struct {
+	version      int
+	cpuQuota     float64 // 核数，0 表示不限制
+	cpuUsageUsec uint64  // 累计 CPU 时间，单位微秒
+	memLimit     uint64  // 0 表示不限制
+	memCurrent   uint64
+	inactiveFile uint64
+	mem          cgroupMemStat
+}
+
+// cgroupMemStat memory.stat 中的内存构成，v1 取 total_ 开头的层级汇总值
+type cgroupMemStat struct {
+	anon            uint64
+	file            uint64
+	shmem           uint64
+	dirty           uint64
+	slabReclaimable uint64 // 仅 v2
+	zswap           uint64 // 仅 v2
+	zswapped        uint64 // 仅 v2
+}
+
+// containerCgroups 是否以容器模式读取 cgroup，只检测一次
+var containerCgroups = sync.OnceValue(func() bool {
+	// 设置了 HostProc 表示希望监控宿主机
+	if runtime.GOOS != "linux" || flags.HostProc != "" {
+		return false
+	}
+	return isContainerVirt(Virtualized())
+})
+
+// isContainerVirt 判断 Virtualized() 的结果是否为可以读取自身 cgroup 限制的容器
+func isContainerVirt(virt string) bool {
+	switch virt {
+	case "docker", "podman", "lxc", "lxc-libvirt", "systemd-nspawn", "rkt", "pouch",
+		"kubernetes", "container", "container-other":
+		return true
+	}
+	return false
+}
+
+// cgroupCPUState 上一次的 cgroup CPU 时间采样
+var cgroupCPUState struct {
+	sync.Mutex
+	usageUsec uint64
+	at        time.Time
+	lastUsage float64
+}
+
+// CgroupSnapshot 一次读取到的 cgroup 数据。同一份报告中的 CPU、内存与 cgroup 字段共用一次读取，
+// 避免重复读取 cgroup 文件，并保证 ram.used 与 cgroup.memory_used 一致
+type CgroupSnapshot struct {
+	stats cgroupStats
+}
+
+// ReadCgroup 在容器中读取一次 cgroup 数据，不在容器中或读取失败时返回 nil
+func ReadCgroup() *CgroupSnapshot {
+	if !containerCgroups() {
+		return nil
+	}
+	stats, ok := readCgroupStats()
+	if !ok {
+		return nil
+	}
+	return &CgroupSnapshot{stats: stats}
+}
+
+// Info 返回 cgroup 限制下的资源情况，CPU 使用率为最近一次 Cpu 采样的结果
+func (s *CgroupSnapshot) Info() CgroupInfo {
+	cgroupCPUState.Lock()
+	usage := cgroupCPUState.lastUsage
+	cgroupCPUState.Unlock()
+	return CgroupInfo{
+		Version:     s.stats.version,
+		CPUQuota:    s.stats.cpuQuota,
+		CPUUsage:    usage,
+		MemoryLimit: s.stats.memLimit,
+		MemoryUsed:  cgroupMemoryUsed(s.stats),
+	}
+}
+
+// cgroupCPUUsage 根据与上一次调用之间的 CPU 时间差计算容器的 CPU 使用率。
+// 首次调用时没有上一次采样，返回 0。
+func cgroupCPUUsage(stats cgroupStats) float64 {
+	now := time.Now()
+	cgroupCPUState.Lock()
+	defer cgroupCPUState.Unlock()
+	usage := 0.0
+	if !cgroupCPUState.at.IsZero() {
+		used := safeCounterDelta(stats.cpuUsageUsec, cgroupCPUState.usageUsec)
+		usage = cgroupCPUPercent(used, now.Sub(cgroupCPUState.at), stats.cpuQuota)
+	}
+	cgroupCPUState.usageUsec, cgroupCPUState.at, cgroupCPUState.lastUsage = stats.cpuUsageUsec, now, usage
+	return usage
+}
+
+// cgroupCPUPercent 将 elapsed 内消耗的 CPU 时间换算为相对于配额的百分比
+func cgroupCPUPercent(usedUsec uint64, elapsed time.Duration, quota float64) float64 {
+	cores := quota
+	if cores <= 0 {
+		// runtime.NumCPU 已考虑 cpuset 限制
+		cores = float64(runtime.NumCPU())
+	}
+	wallUsec := float64(elapsed.Microseconds())
+	if wallUsec <= 0 || cores <= 0 {
+		return 0
+	}
+	percent := float64(usedUsec) / (wallUsec * cores) * 100
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// cgroupMemoryUsed 与 docker stats 一致扣除不活跃的文件缓存，--memory-include-cache 时使用原始值
+func cgroupMemoryUsed(stats cgroupStats) uint64 {
+	if flags.MemoryIncludeCache || stats.inactiveFile > stats.memCurrent {
+		return stats.memCurrent
+	}
+	return stats.memCurrent - stats.inactiveFile
+}
+
+// cgroupMemDetail 以 cgroup 的 memory.stat 构造内存明细，宿主机级别的字段（buffers、大页）不适用，留空。
+// 未限制内存时无法确定可用内存，available 与 free 留空
+func cgroupMemDetail(stats cgroupStats) MemDetail {
+	detail := MemDetail{
+		Mode:         "cgroup",
+		Cached:       stats.mem.file,
+		Shared:       stats.mem.shmem,
+		SReclaimable: stats.mem.slabReclaimable,
+		Dirty:        stats.mem.dirty,
+		Anon:         stats.mem.anon,
+		InactiveFile: stats.inactiveFile,
+		Zswap:        stats.mem.zswap,
+		Zswapped:     stats.mem.zswapped,
+	}
+	if stats.memLimit > 0 {
+		if used := cgroupMemoryUsed(stats); used < stats.memLimit {
+			detail.Available = stats.memLimit - used
+		}
+		if stats.memCurrent < stats.memLimit {
+			detail.Free = stats.memLimit - stats.memCurrent
+		}
+	}
+	return detail
+}
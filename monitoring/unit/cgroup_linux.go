@@ -0,0 +1,222 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupRoot cgroup 文件系统的挂载点
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupV1Unlimited cgroup v1 中不小于此值的内存上限视为不限制（未设置时为接近 int64 最大值的页对齐数）
+const cgroupV1Unlimited = 1 << 62
+
+func readCgroupStats() (cgroupStats, bool) {
+	self, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return cgroupStats{}, false
+	}
+	return readCgroupStatsFrom(cgroupRoot, string(self))
+}
+
+// readCgroupStatsFrom 根据 /proc/self/cgroup 的内容在 root 下定位并读取当前进程的 cgroup
+func readCgroupStatsFrom(root, self string) (cgroupStats, bool) {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		dir := cgroupLimitDir(root, cgroupPath(self, ""), cgroupV2Limited)
+		if dir == "" {
+			// 未设置限制时读取容器的顶层 cgroup（cgroup 命名空间的根）；
+			// 未启用命名空间时根为宿主机的根 cgroup，没有 memory.current，只能读取自身所在的 cgroup
+			dir = cgroupDir(root, cgroupPath(self, ""))
+			if _, err := os.Stat(filepath.Join(root, "memory.current")); err == nil {
+				dir = root
+			}
+		}
+		return readCgroupV2(dir)
+	}
+	return readCgroupV1(root, self)
+}
+
+// cgroupLimitDir 从当前进程所在的 cgroup 向上查找最近的设置了限制的 cgroup，直到 base 为止；
+// 都没有限制时返回空字符串。容器内的服务（如 LXC 中由 systemd 启动的 Agent）位于容器 cgroup 的子级，
+// 限制设置在容器的顶层 cgroup 上
+func cgroupLimitDir(base, path string, limited func(dir string) bool) string {
+	base = filepath.Clean(base)
+	for dir := cgroupDir(base, path); ; dir = filepath.Dir(dir) {
+		if limited(dir) {
+			return dir
+		}
+		if dir == base || !strings.HasPrefix(dir, base) {
+			return ""
+		}
+	}
+}
+
+// cgroupV2Limited 是否设置了内存或 CPU 限制
+func cgroupV2Limited(dir string) bool {
+	if limit, ok := readCgroupUint(filepath.Join(dir, "memory.max")); ok && limit > 0 {
+		return true
+	}
+	fields := strings.Fields(readSysString(filepath.Join(dir, "cpu.max")))
+	return len(fields) == 2 && fields[0] != "max"
+}
+
+// cgroupPath 从 /proc/self/cgroup 中找出指定控制器的路径，controller 为空时查找 v2 的 "0::" 行
+func cgroupPath(self, controller string) string {
+	for _, line := range strings.Split(self, "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if controller == "" {
+			if parts[0] == "0" && parts[1] == "" {
+				return parts[2]
+			}
+			continue
+		}
+		for _, c := range strings.Split(parts[1], ",") {
+			if c == controller {
+				return parts[2]
+			}
+		}
+	}
+	return "/"
+}
+
+// cgroupDir 优先使用完整路径；启用 cgroup 命名空间或容器只挂载了自身 cgroup 时，路径下不存在对应目录，回退到挂载点
+func cgroupDir(base, path string) string {
+	dir := filepath.Join(base, path)
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir
+	}
+	return base
+}
+
+func readCgroupV2(dir string) (cgroupStats, bool) {
+	stats := cgroupStats{version: 2}
+	current, ok := readCgroupUint(filepath.Join(dir, "memory.current"))
+	if !ok {
+		return stats, false
+	}
+	stats.memCurrent = current
+	if limit, ok := readCgroupUint(filepath.Join(dir, "memory.max")); ok {
+		stats.memLimit = limit
+	}
+	memStat := readCgroupStatFile(filepath.Join(dir, "memory.stat"))
+	stats.inactiveFile = memStat["inactive_file"]
+	stats.mem = cgroupMemStat{
+		anon:            memStat["anon"],
+		file:            memStat["file"],
+		shmem:           memStat["shmem"],
+		dirty:           memStat["file_dirty"],
+		slabReclaimable: memStat["slab_reclaimable"],
+		zswap:           memStat["zswap"],
+		zswapped:        memStat["zswapped"],
+	}
+
+	// cpu.max 的格式为 "$MAX $PERIOD"，$MAX 为 max 表示不限制
+	if fields := strings.Fields(readSysString(filepath.Join(dir, "cpu.max"))); len(fields) == 2 {
+		quota, err1 := strconv.ParseFloat(fields[0], 64)
+		period, err2 := strconv.ParseFloat(fields[1], 64)
+		if err1 == nil && err2 == nil && quota > 0 && period > 0 {
+			stats.cpuQuota = quota / period
+		}
+	}
+	stats.cpuUsageUsec = readCgroupStatFile(filepath.Join(dir, "cpu.stat"))["usage_usec"]
+	return stats, true
+}
+
+func readCgroupV1(root, self string) (cgroupStats, bool) {
+	stats := cgroupStats{version: 1}
+	memDir := cgroupV1Dir(filepath.Join(root, "memory"), cgroupPath(self, "memory"), func(dir string) bool {
+		limit, ok := readCgroupUint(filepath.Join(dir, "memory.limit_in_bytes"))
+		return ok && limit > 0 && limit < cgroupV1Unlimited
+	})
+	current, ok := readCgroupUint(filepath.Join(memDir, "memory.usage_in_bytes"))
+	if !ok {
+		return stats, false
+	}
+	stats.memCurrent = current
+	if limit, ok := readCgroupUint(filepath.Join(memDir, "memory.limit_in_bytes")); ok && limit < cgroupV1Unlimited {
+		stats.memLimit = limit
+	}
+	memStat := readCgroupStatFile(filepath.Join(memDir, "memory.stat"))
+	stats.inactiveFile = memStat["total_inactive_file"]
+	stats.mem = cgroupMemStat{
+		anon:  memStat["total_rss"],
+		file:  memStat["total_cache"],
+		shmem: memStat["total_shmem"],
+		dirty: memStat["total_dirty"],
+	}
+
+	// cfs_quota_us 为 -1 表示不限制
+	cpuDir := cgroupV1Dir(filepath.Join(root, "cpu"), cgroupPath(self, "cpu"), func(dir string) bool {
+		quota, err := strconv.ParseFloat(readSysString(filepath.Join(dir, "cpu.cfs_quota_us")), 64)
+		return err == nil && quota > 0
+	})
+	quota, err1 := strconv.ParseFloat(readSysString(filepath.Join(cpuDir, "cpu.cfs_quota_us")), 64)
+	period, err2 := strconv.ParseFloat(readSysString(filepath.Join(cpuDir, "cpu.cfs_period_us")), 64)
+	if err1 == nil && err2 == nil && quota > 0 && period > 0 {
+		stats.cpuQuota = quota / period
+	}
+	// cpu 与 cpuacct 通常挂载在同一层级，优先与配额取自同一 cgroup；cpuacct.usage 单位为纳秒
+	usage, ok := readCgroupUint(filepath.Join(cpuDir, "cpuacct.usage"))
+	if !ok {
+		acctDir := cgroupDir(filepath.Join(root, "cpuacct"), cgroupPath(self, "cpuacct"))
+		usage, ok = readCgroupUint(filepath.Join(acctDir, "cpuacct.usage"))
+	}
+	if ok {
+		stats.cpuUsageUsec = usage / 1000
+	}
+	return stats, true
+}
+
+// cgroupV1Dir 返回控制器下最近的设置了限制的 cgroup，都没有限制时使用当前进程所在的 cgroup。
+// v1 未启用命名空间时挂载点为宿主机的根 cgroup，不能回退到挂载点
+func cgroupV1Dir(base, path string, limited func(dir string) bool) string {
+	if dir := cgroupLimitDir(base, path, limited); dir != "" {
+		return dir
+	}
+	return cgroupDir(base, path)
+}
+
+// readCgroupUint 读取单个数值，"max" 表示不限制，返回 0
+func readCgroupUint(path string) (uint64, bool) {
+	s := readSysString(path)
+	if s == "" {
+		return 0, false
+	}
+	if s == "max" {
+		return 0, true
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// readCgroupStatFile 读取 "key value" 格式的文件（memory.stat、cpu.stat）中的全部字段
+func readCgroupStatFile(path string) map[string]uint64 {
+	stats := make(map[string]uint64)
+	file, err := os.Open(path)
+	if err != nil {
+		return stats
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			stats[fields[0]] = v
+		}
+	}
+	return stats
+}
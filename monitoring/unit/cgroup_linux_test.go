@@ -0,0 +1,149 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"testing"
+)
+
+func TestReadCgroupV2(t *testing.T) {
+	root := t.TempDir()
+	writeSysFile(t, root, "cgroup.controllers", "cpu memory io")
+	writeSysFile(t, root, "memory.current", "104857600")
+	writeSysFile(t, root, "memory.max", "536870912")
+	writeSysFile(t, root, "memory.stat", "anon 60000000\nfile 40000000\nshmem 1000000\nfile_dirty 4096\nslab_reclaimable 2000000\ninactive_file 20000000")
+	writeSysFile(t, root, "cpu.max", "150000 100000")
+	writeSysFile(t, root, "cpu.stat", "usage_usec 123456\nuser_usec 100000\nsystem_usec 23456")
+
+	// 启用了 cgroup 命名空间，路径为 /
+	stats, ok := readCgroupStatsFrom(root, "0::/\n")
+	if !ok {
+		t.Fatal("expected cgroup v2 stats")
+	}
+	want := cgroupStats{version: 2, cpuQuota: 1.5, cpuUsageUsec: 123456, memLimit: 536870912, memCurrent: 104857600, inactiveFile: 20000000,
+		mem: cgroupMemStat{anon: 60000000, file: 40000000, shmem: 1000000, dirty: 4096, slabReclaimable: 2000000}}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	// LXC 中由 systemd 启动时位于容器内的服务 cgroup，限制设置在容器的顶层 cgroup 上
+	writeSysFile(t, root, "system.slice/komari-agent.service/memory.current", "1000")
+	writeSysFile(t, root, "system.slice/komari-agent.service/memory.max", "max")
+	writeSysFile(t, root, "system.slice/komari-agent.service/cpu.max", "max 100000")
+	stats, ok = readCgroupStatsFrom(root, "0::/system.slice/komari-agent.service\n")
+	if !ok || stats != want {
+		t.Errorf("nested service stats = %+v, ok=%v, want container root %+v", stats, ok, want)
+	}
+
+	// 服务自身设置了限制时使用最近的设置了限制的 cgroup
+	writeSysFile(t, root, "system.slice/komari-agent.service/memory.max", "4096")
+	stats, ok = readCgroupStatsFrom(root, "0::/system.slice/komari-agent.service\n")
+	if !ok || stats.memLimit != 4096 || stats.memCurrent != 1000 {
+		t.Errorf("limited service stats = %+v, ok=%v", stats, ok)
+	}
+}
+
+func TestReadCgroupV2WithoutNamespace(t *testing.T) {
+	// 未启用 cgroup 命名空间时挂载点为宿主机的根 cgroup，没有 memory.current
+	root := t.TempDir()
+	writeSysFile(t, root, "cgroup.controllers", "cpu memory io")
+	writeSysFile(t, root, "system.slice/docker-abc.scope/memory.current", "1000")
+	writeSysFile(t, root, "system.slice/docker-abc.scope/memory.max", "max")
+	writeSysFile(t, root, "system.slice/docker-abc.scope/cpu.max", "max 100000")
+	stats, ok := readCgroupStatsFrom(root, "0::/system.slice/docker-abc.scope\n")
+	if !ok || stats.memLimit != 0 || stats.cpuQuota != 0 || stats.memCurrent != 1000 {
+		t.Errorf("unlimited stats = %+v, ok=%v", stats, ok)
+	}
+}
+
+func TestReadCgroupV1(t *testing.T) {
+	root := t.TempDir()
+	writeSysFile(t, root, "memory/memory.usage_in_bytes", "2000000")
+	writeSysFile(t, root, "memory/memory.limit_in_bytes", "9223372036854771712")
+	writeSysFile(t, root, "memory/memory.stat", "cache 500000\ntotal_cache 600000\ntotal_rss 1200000\ntotal_inactive_file 300000")
+	writeSysFile(t, root, "cpu/cpu.cfs_quota_us", "50000")
+	writeSysFile(t, root, "cpu/cpu.cfs_period_us", "100000")
+	writeSysFile(t, root, "cpuacct/cpuacct.usage", "5000000000")
+
+	self := "12:cpu,cpuacct:/docker/abc\n4:memory:/docker/abc\n0::/\n"
+	stats, ok := readCgroupStatsFrom(root, self)
+	if !ok {
+		t.Fatal("expected cgroup v1 stats")
+	}
+	want := cgroupStats{version: 1, cpuQuota: 0.5, cpuUsageUsec: 5000000, memLimit: 0, memCurrent: 2000000, inactiveFile: 300000,
+		mem: cgroupMemStat{anon: 1200000, file: 600000}}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+	if used := cgroupMemoryUsed(stats); used != 1700000 {
+		t.Errorf("memory used = %d, want 1700000", used)
+	}
+
+	// 容器内服务的 cgroup 未设置限制时向上使用容器 cgroup 的限制
+	writeSysFile(t, root, "memory/memory.limit_in_bytes", "1073741824")
+	writeSysFile(t, root, "memory/system.slice/agent.service/memory.usage_in_bytes", "1000")
+	writeSysFile(t, root, "memory/system.slice/agent.service/memory.limit_in_bytes", "9223372036854771712")
+	stats, ok = readCgroupStatsFrom(root, "4:memory:/system.slice/agent.service\n")
+	if !ok || stats.memLimit != 1073741824 || stats.memCurrent != 2000000 {
+		t.Errorf("nested v1 stats = %+v, ok=%v", stats, ok)
+	}
+}
+
+func TestCgroupMemDetail(t *testing.T) {
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+	flags.MemoryIncludeCache = false
+
+	stats := cgroupStats{memLimit: 1000, memCurrent: 600, inactiveFile: 100,
+		mem: cgroupMemStat{anon: 300, file: 250, shmem: 20, dirty: 5}}
+	got := cgroupMemDetail(stats)
+	want := MemDetail{Mode: "cgroup", Available: 500, Free: 400, Cached: 250, Shared: 20, Dirty: 5, Anon: 300, InactiveFile: 100}
+	if got != want {
+		t.Errorf("detail = %+v\nwant     %+v", got, want)
+	}
+
+	// 未限制内存时不上报可用内存，也不使用宿主机的数据
+	stats.memLimit = 0
+	if got := cgroupMemDetail(stats); got.Available != 0 || got.Free != 0 || got.Cached != 250 {
+		t.Errorf("unlimited detail = %+v", got)
+	}
+}
+
+func TestCgroupHelpers(t *testing.T) {
+	// 1.5 核配额下 2 秒内消耗 1.5 秒 CPU 时间
+	if got := cgroupCPUPercent(1500000, 2e9, 1.5); got != 50 {
+		t.Errorf("cpu percent = %v, want 50", got)
+	}
+	if got := cgroupCPUPercent(10000000, 1e9, 1); got != 100 {
+		t.Errorf("cpu percent = %v, want clamped 100", got)
+	}
+	for virt, want := range map[string]bool{"docker": true, "lxc": true, "kubernetes": true, "kvm": false, "none": false, "wsl": false} {
+		if got := isContainerVirt(virt); got != want {
+			t.Errorf("isContainerVirt(%q) = %v, want %v", virt, got, want)
+		}
+	}
+}
+
+func TestCgroupSnapshotSharedAcrossReport(t *testing.T) {
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+	flags.MemoryIncludeCache = false
+
+	cg := &CgroupSnapshot{stats: cgroupStats{version: 2, cpuQuota: 1.5, memLimit: 1000, memCurrent: 600, inactiveFile: 100,
+		mem: cgroupMemStat{anon: 300, file: 250}}}
+	// 同一份快照得到的 ram、memory 与 cgroup 字段一致，且不读取 cgroup 文件
+	ram := Ram(cg)
+	info := cg.Info()
+	if ram.Mode != "cgroup" || ram.Total != 1000 || ram.Used != info.MemoryUsed || ram.Used != 500 {
+		t.Errorf("ram = %+v, cgroup = %+v", ram, info)
+	}
+	if detail := MemoryDetail(ram.Mode, cg); detail.Available != ram.Total-ram.Used || detail.Cached != 250 {
+		t.Errorf("detail = %+v", detail)
+	}
+	if cpu := Cpu(cg); cpu.CPUCores != 2 {
+		t.Errorf("cpu cores = %d, want quota rounded up to 2", cpu.CPUCores)
+	}
+	if detail := MemoryDetail("cgroup", nil); detail != (MemDetail{Mode: "cgroup"}) {
+		t.Errorf("detail without snapshot = %+v", detail)
+	}
+}
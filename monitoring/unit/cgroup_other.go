@@ -0,0 +1,8 @@
+//go:build !linux
+
+package monitoring
+
+// readCgroupStats cgroup 仅存在于 Linux
+func readCgroupStats() (cgroupStats, bool) {
+	return cgroupStats{}, false
+}
@@ -2,6 +2,7 @@ package monitoring
 
 import (
 	"bufio"
+	"math"
 	"os"
 	"runtime"
 	"strings"
@@ -20,8 +21,15 @@ type CpuInfo struct {
 	CPUUsage         float64 `json:"cpu_usage"`
 }
 
-func Cpu() CpuInfo {
-	cpuinfo := CpuStaticInfo()
+// Cpu 返回 CPU 信息与使用率，cg 为本次报告读取的 cgroup 数据，不在容器中时为 nil
+func Cpu(cg *CgroupSnapshot) CpuInfo {
+	cpuinfo := cpuStaticInfo(cg)
+
+	// 容器中使用 cgroup 的 CPU 时间，使用率相对于容器的配额
+	if cg != nil {
+		cpuinfo.CPUUsage = cgroupCPUUsage(cg.stats)
+		return cpuinfo
+	}
 
 	percentages, err := cpu.Percent(0, false)
 	if err == nil && len(percentages) > 0 {
@@ -32,6 +40,10 @@ func Cpu() CpuInfo {
 }
 
 func CpuStaticInfo() CpuInfo {
+	return cpuStaticInfo(ReadCgroup())
+}
+
+func cpuStaticInfo(cg *CgroupSnapshot) CpuInfo {
 	cpuinfo := CpuInfo{
 		CPUName:          "Unknown",
 		CPUArchitecture:  runtime.GOARCH,
@@ -68,6 +80,11 @@ func CpuStaticInfo() CpuInfo {
 		cpuinfo.CPUPhysicalCores = physicalCores
 	}
 
+	// 容器设置了 CPU 配额时以配额（向上取整）作为核心数
+	if cg != nil && cg.stats.cpuQuota > 0 {
+		cpuinfo.CPUCores = int(math.Ceil(cg.stats.cpuQuota))
+	}
+
 	return cpuinfo
 }
 
@@ -0,0 +1,118 @@
+package monitoring
+
+import (
+	"sync"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+)
+
+// CpuTimes 两次采样之间各类 CPU 时间所占的百分比
+type CpuTimes struct {
+	User    float64 `json:"user"`
+	System  float64 `json:"system"`
+	Nice    float64 `json:"nice"`
+	Iowait  float64 `json:"iowait"`
+	Irq     float64 `json:"irq"`
+	Softirq float64 `json:"softirq"`
+	Steal   float64 `json:"steal"`
+	Idle    float64 `json:"idle"`
+}
+
+// CpuTimesInfo 整体 CPU 时间分布与每个逻辑核心的使用率
+type CpuTimesInfo struct {
+	Total   CpuTimes
+	PerCore []float64 // 未请求或采集失败时为 nil
+}
+
+// cpuTimesState 上一次的 cpu.Times 采样，用于计算差值
+var cpuTimesState struct {
+	sync.Mutex
+	total   *cpu.TimesStat
+	perCore []cpu.TimesStat
+}
+
+// CpuTimesDetail 根据与上一次调用之间 cpu.Times 的差值计算 CPU 时间分布。
+// 首次调用时没有上一次采样，结果为开机以来的平均值。
+func CpuTimesDetail(perCore bool) (CpuTimesInfo, error) {
+	var info CpuTimesInfo
+
+	totals, err := cpu.Times(false)
+	if err != nil || len(totals) == 0 {
+		return info, err
+	}
+	var cores []cpu.TimesStat
+	if perCore {
+		// 单核数据采集失败不影响整体数据
+		cores, _ = cpu.Times(true)
+	}
+
+	cpuTimesState.Lock()
+	defer cpuTimesState.Unlock()
+
+	info.Total = cpuTimesBreakdown(cpuTimesState.total, totals[0])
+	cpuTimesState.total = &totals[0]
+
+	if len(cores) > 0 {
+		info.PerCore = make([]float64, len(cores))
+		for i, cur := range cores {
+			var prev *cpu.TimesStat
+			// 核心数量变化（CPU 热插拔）时从头计算
+			if len(cpuTimesState.perCore) == len(cores) {
+				prev = &cpuTimesState.perCore[i]
+			}
+			info.PerCore[i] = cpuBusyPercent(cpuTimesBreakdown(prev, cur))
+		}
+		cpuTimesState.perCore = cores
+	}
+	return info, nil
+}
+
+// cpuTimesBreakdown 计算 prev 到 cur 之间各类时间的百分比，prev 为 nil 时使用 cur 的累计值
+func cpuTimesBreakdown(prev *cpu.TimesStat, cur cpu.TimesStat) CpuTimes {
+	d := cur
+	if prev != nil {
+		d = cpu.TimesStat{
+			User:      cur.User - prev.User,
+			System:    cur.System - prev.System,
+			Idle:      cur.Idle - prev.Idle,
+			Nice:      cur.Nice - prev.Nice,
+			Iowait:    cur.Iowait - prev.Iowait,
+			Irq:       cur.Irq - prev.Irq,
+			Softirq:   cur.Softirq - prev.Softirq,
+			Steal:     cur.Steal - prev.Steal,
+			Guest:     cur.Guest - prev.Guest,
+			GuestNice: cur.GuestNice - prev.GuestNice,
+		}
+	}
+	// Linux 的 user/nice 已包含 guest 时间，不重复计算
+	total := d.User + d.System + d.Idle + d.Nice + d.Iowait + d.Irq + d.Softirq + d.Steal
+	if total <= 0 {
+		// 两次采样间隔过短或计数器回绕
+		return CpuTimes{Idle: 100}
+	}
+	pct := func(v float64) float64 {
+		if v < 0 {
+			return 0
+		}
+		return v / total * 100
+	}
+	return CpuTimes{
+		User:    pct(d.User),
+		System:  pct(d.System),
+		Nice:    pct(d.Nice),
+		Iowait:  pct(d.Iowait),
+		Irq:     pct(d.Irq),
+		Softirq: pct(d.Softirq),
+		Steal:   pct(d.Steal),
+		Idle:    pct(d.Idle),
+	}
+}
+
+// cpuBusyPercent 与 cpu.Percent 一致，将 idle 与 iowait 之外的时间视为繁忙
+func cpuBusyPercent(t CpuTimes) float64 {
+	busy := 100 - t.Idle - t.Iowait
+	if busy < 0 {
+		return 0
+	}
+	return busy
+}
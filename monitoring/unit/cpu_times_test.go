@@ -0,0 +1,49 @@
+package monitoring
+
+import (
+	"math"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+)
+
+func TestCpuTimesBreakdownUsesDelta(t *testing.T) {
+	prev := cpu.TimesStat{User: 100, System: 50, Idle: 800, Iowait: 10, Steal: 5}
+	cur := cpu.TimesStat{User: 120, System: 60, Idle: 850, Iowait: 15, Steal: 20, Guest: 10}
+
+	got := cpuTimesBreakdown(&prev, cur)
+	// 总差值 20+10+50+5+15 = 100，guest 已包含在 user 中不重复计算
+	want := CpuTimes{User: 20, System: 10, Idle: 50, Iowait: 5, Steal: 15}
+	if got != want {
+		t.Fatalf("breakdown = %+v, want %+v", got, want)
+	}
+	if busy := cpuBusyPercent(got); math.Abs(busy-45) > 1e-9 {
+		t.Errorf("busy = %v, want 45", busy)
+	}
+}
+
+func TestCpuTimesBreakdownWithoutProgress(t *testing.T) {
+	s := cpu.TimesStat{User: 10, Idle: 10}
+	if got := cpuTimesBreakdown(&s, s); got != (CpuTimes{Idle: 100}) {
+		t.Errorf("breakdown without progress = %+v, want idle 100", got)
+	}
+	if got := cpuTimesBreakdown(nil, s); got.User != 50 || got.Idle != 50 {
+		t.Errorf("breakdown since boot = %+v, want user 50 idle 50", got)
+	}
+}
+
+func TestCpuTimesDetail(t *testing.T) {
+	if _, err := CpuTimesDetail(true); err != nil {
+		t.Skipf("cpu times unavailable: %v", err)
+	}
+	info, err := CpuTimesDetail(true)
+	if err != nil {
+		t.Fatalf("CpuTimesDetail failed: %v", err)
+	}
+	for i, usage := range info.PerCore {
+		if usage < 0 || usage > 100 {
+			t.Errorf("core %d usage out of range: %v", i, usage)
+		}
+	}
+	t.Logf("CPU times: %+v, per core: %v", info.Total, info.PerCore)
+}
@@ -21,18 +21,14 @@ func Disk() DiskInfo {
 		diskinfo.Used = 0
 	} else {
 		// 如果指定了自定义挂载点，只统计指定的挂载点
-		if flags.IncludeMountpoints != "" {
-			includeMounts := strings.Split(flags.IncludeMountpoints, ";")
+		if includeMounts := includedMountpoints(); len(includeMounts) > 0 {
 			for _, mountpoint := range includeMounts {
-				mountpoint = strings.TrimSpace(mountpoint)
-				if mountpoint != "" {
-					u, err := disk.Usage(mountpoint)
-					if err != nil {
-						continue
-					} else {
-						diskinfo.Total += u.Total
-						diskinfo.Used += u.Used
-					}
+				u, err := disk.Usage(mountpoint)
+				if err != nil {
+					continue
+				} else {
+					diskinfo.Total += u.Total
+					diskinfo.Used += u.Used
 				}
 			}
 		} else {
@@ -46,13 +42,7 @@ func Disk() DiskInfo {
 						continue
 					}
 
-					deviceID := part.Device
-					// ZFS去重: 基于 pool 名称 (例如 pool/dataset -> pool)
-					if strings.ToLower(part.Fstype) == "zfs" {
-						if idx := strings.Index(deviceID, "/"); idx != -1 {
-							deviceID = deviceID[:idx]
-						}
-					}
+					deviceID := diskDeviceID(part)
 
 					// 如果该设备已存在，且当前挂载点的 Total 更大，则替换（处理 quota 等情况）
 					// 否则保留现有的（通常我们希望统计物理 pool 的总量）
@@ -75,6 +65,28 @@ func Disk() DiskInfo {
 	return diskinfo
 }
 
+// includedMountpoints 解析 --include-mountpoint 指定的挂载点列表
+func includedMountpoints() []string {
+	var mountpoints []string
+	for _, mountpoint := range strings.Split(flags.IncludeMountpoints, ";") {
+		if mountpoint = strings.TrimSpace(mountpoint); mountpoint != "" {
+			mountpoints = append(mountpoints, mountpoint)
+		}
+	}
+	return mountpoints
+}
+
+// diskDeviceID 返回用于去重的设备标识，ZFS 基于 pool 名称 (例如 pool/dataset -> pool)
+func diskDeviceID(part disk.PartitionStat) string {
+	deviceID := part.Device
+	if strings.ToLower(part.Fstype) == "zfs" {
+		if idx := strings.Index(deviceID, "/"); idx != -1 {
+			deviceID = deviceID[:idx]
+		}
+	}
+	return deviceID
+}
+
 // isPhysicalDisk 判断分区是否为物理磁盘
 func isPhysicalDisk(part disk.PartitionStat) bool {
 	// 对于LXC等基于loop的根文件系统，始终包含根挂载点
@@ -158,14 +170,8 @@ func isPhysicalDisk(part disk.PartitionStat) bool {
 
 func DiskList() ([]string, error) {
 	diskList := []string{}
-	if flags.IncludeMountpoints != "" {
-		includeMounts := strings.Split(flags.IncludeMountpoints, ";")
-		for _, mountpoint := range includeMounts {
-			mountpoint = strings.TrimSpace(mountpoint)
-			if mountpoint != "" {
-				diskList = append(diskList, mountpoint)
-			}
-		}
+	if includeMounts := includedMountpoints(); len(includeMounts) > 0 {
+		diskList = append(diskList, includeMounts...)
 	} else {
 		usage, err := disk.Partitions(true)
 		if err != nil {
@@ -176,13 +182,7 @@ func DiskList() ([]string, error) {
 		deviceMap := make(map[string]disk.PartitionStat)
 		for _, part := range usage {
 			if isPhysicalDisk(part) {
-				deviceID := part.Device
-				// ZFS去重: 基于 pool 名称
-				if strings.ToLower(part.Fstype) == "zfs" {
-					if idx := strings.Index(deviceID, "/"); idx != -1 {
-						deviceID = deviceID[:idx]
-					}
-				}
+				deviceID := diskDeviceID(part)
 
 				if existing, ok := deviceMap[deviceID]; ok {
 					// 优先保留路径更短的挂载点 (e.g., /volume1 优于 /volume1/@appdata/...)
@@ -0,0 +1,176 @@
+package monitoring
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// DiskIOInfo 单个块设备在两次采样之间的 I/O 速率
+type DiskIOInfo struct {
+	Device     string  `json:"device"` // 块设备名，ZFS 为 pool 名称
+	Mountpoint string  `json:"mountpoint"`
+	ReadBytes  uint64  `json:"read_bytes"`  // 每秒读取字节数
+	WriteBytes uint64  `json:"write_bytes"` // 每秒写入字节数
+	ReadIOPS   float64 `json:"read_iops"`
+	WriteIOPS  float64 `json:"write_iops"`
+	Await      float64 `json:"await"` // 平均每次 I/O 耗时，单位毫秒；ZFS pool 无法获取，为 0
+	Util       float64 `json:"util"`  // 设备忙碌时间占比 (0-100)；新版 OpenZFS 无法获取，为 0
+}
+
+// diskIOState 上一次的 I/O 计数器采样，用于计算速率
+var diskIOState struct {
+	sync.Mutex
+	counters map[string]disk.IOCountersStat
+	at       time.Time
+}
+
+// DiskIO 返回与 Disk() 统计范围相同的各设备 I/O 速率。
+// 首次调用只记录基准值，返回空列表。
+func DiskIO() ([]DiskIOInfo, error) {
+	parts, err := disk.Partitions(true)
+	if err != nil {
+		return nil, err
+	}
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return nil, err
+	}
+	addZFSPoolCounters(parts, counters)
+	now := time.Now()
+	devices := selectIODevices(parts, includedMountpoints(), counters)
+
+	diskIOState.Lock()
+	defer diskIOState.Unlock()
+	prev, elapsed := diskIOState.counters, now.Sub(diskIOState.at)
+	diskIOState.counters, diskIOState.at = counters, now
+	if prev == nil {
+		return nil, nil
+	}
+	return diskIORates(devices, prev, counters, elapsed), nil
+}
+
+// addZFSPoolCounters 将分区中出现的 ZFS pool 的计数器加入 counters，与块设备重名时保留块设备
+func addZFSPoolCounters(parts []disk.PartitionStat, counters map[string]disk.IOCountersStat) {
+	var pools []string
+	seen := make(map[string]bool)
+	for _, part := range parts {
+		if strings.ToLower(part.Fstype) != "zfs" {
+			continue
+		}
+		if pool := diskDeviceID(part); !seen[pool] {
+			seen[pool] = true
+			pools = append(pools, pool)
+		}
+	}
+	if len(pools) == 0 {
+		return
+	}
+	for name, c := range zfsPoolCounters(pools) {
+		if _, ok := counters[name]; !ok {
+			counters[name] = c
+		}
+	}
+}
+
+// selectIODevices 按与容量统计相同的规则选择分区，返回 I/O 计数器名称到挂载点的映射
+func selectIODevices(parts []disk.PartitionStat, includeMounts []string, counters map[string]disk.IOCountersStat) map[string]string {
+	// 设备映射器等设备的计数器名称为 dm-N，标签才是 /dev/mapper 下的名称
+	byLabel := make(map[string]string)
+	for name, c := range counters {
+		if c.Label != "" {
+			byLabel[c.Label] = name
+		}
+	}
+	counterName := func(device string) string {
+		for _, candidate := range []string{device, strings.TrimPrefix(device, "/dev/"), filepath.Base(device)} {
+			if _, ok := counters[candidate]; ok {
+				return candidate
+			}
+			if name, ok := byLabel[candidate]; ok {
+				return name
+			}
+		}
+		return ""
+	}
+
+	var include map[string]bool
+	if len(includeMounts) > 0 {
+		include = make(map[string]bool)
+		for _, mountpoint := range includeMounts {
+			include[mountpoint] = true
+		}
+	}
+
+	// 同一设备只保留路径最短的挂载点
+	devices := make(map[string]string)
+	for _, part := range parts {
+		if include != nil {
+			if !include[part.Mountpoint] {
+				continue
+			}
+		} else if !isPhysicalDisk(part) {
+			continue
+		}
+		// ZFS 数据集的设备名是 pool/dataset，同一 pool 的数据集合并到 pool 的计数器
+		var name string
+		if strings.ToLower(part.Fstype) == "zfs" {
+			if _, ok := counters[diskDeviceID(part)]; ok {
+				name = diskDeviceID(part)
+			}
+		} else {
+			name = counterName(part.Device)
+		}
+		if name == "" {
+			continue
+		}
+		if existing, ok := devices[name]; !ok || len(part.Mountpoint) < len(existing) {
+			devices[name] = part.Mountpoint
+		}
+	}
+	return devices
+}
+
+// diskIORates 根据两次计数器采样计算各设备的速率，计数器回绕或设备新出现时跳过该设备
+func diskIORates(devices map[string]string, prev, cur map[string]disk.IOCountersStat, elapsed time.Duration) []DiskIOInfo {
+	seconds := elapsed.Seconds()
+	if seconds <= 0 {
+		return nil
+	}
+	var result []DiskIOInfo
+	for name, mountpoint := range devices {
+		p, ok := prev[name]
+		c, ok2 := cur[name]
+		if !ok || !ok2 ||
+			c.ReadBytes < p.ReadBytes || c.WriteBytes < p.WriteBytes ||
+			c.ReadCount < p.ReadCount || c.WriteCount < p.WriteCount ||
+			c.ReadTime < p.ReadTime || c.WriteTime < p.WriteTime || c.IoTime < p.IoTime {
+			continue
+		}
+		reads := c.ReadCount - p.ReadCount
+		writes := c.WriteCount - p.WriteCount
+		info := DiskIOInfo{
+			Device:     name,
+			Mountpoint: mountpoint,
+			ReadBytes:  uint64(float64(c.ReadBytes-p.ReadBytes) / seconds),
+			WriteBytes: uint64(float64(c.WriteBytes-p.WriteBytes) / seconds),
+			ReadIOPS:   float64(reads) / seconds,
+			WriteIOPS:  float64(writes) / seconds,
+		}
+		if ops := reads + writes; ops > 0 {
+			info.Await = float64((c.ReadTime-p.ReadTime)+(c.WriteTime-p.WriteTime)) / float64(ops)
+		}
+		// IoTime 单位为毫秒
+		info.Util = float64(c.IoTime-p.IoTime) / (seconds * 1000) * 100
+		if info.Util > 100 {
+			info.Util = 100
+		}
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Device < result[j].Device })
+	return result
+}
@@ -0,0 +1,99 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// zfsPoolCounters 读取 ZFS pool 的 I/O 计数器。ZFS 数据集没有对应的块设备，
+// 以 pool 名称作为计数器名称，与 diskDeviceID 的去重规则一致
+func zfsPoolCounters(pools []string) map[string]disk.IOCountersStat {
+	return readZFSPoolCounters(filepath.Join(procRoot(), "spl", "kstat", "zfs"), pools)
+}
+
+// readZFSPoolCounters 优先读取旧版 OpenZFS 提供的 pool 级 io kstat（含忙碌时间）；
+// 2.1 起该文件被移除，改为汇总各数据集 objset-0x* kstat 中的读写次数与字节数
+func readZFSPoolCounters(root string, pools []string) map[string]disk.IOCountersStat {
+	counters := make(map[string]disk.IOCountersStat)
+	for _, pool := range pools {
+		dir := filepath.Join(root, pool)
+		if c, ok := readZFSPoolIO(filepath.Join(dir, "io")); ok {
+			c.Name = pool
+			counters[pool] = c
+			continue
+		}
+		objsets, _ := filepath.Glob(filepath.Join(dir, "objset-0x*"))
+		if len(objsets) == 0 {
+			continue
+		}
+		c := disk.IOCountersStat{Name: pool}
+		for _, path := range objsets {
+			stats := readZFSKstatNamed(path)
+			c.ReadCount += stats["reads"]
+			c.WriteCount += stats["writes"]
+			c.ReadBytes += stats["nread"]
+			c.WriteBytes += stats["nwritten"]
+		}
+		counters[pool] = c
+	}
+	return counters
+}
+
+// readZFSPoolIO 解析 kstat_io 格式：首行为 kstat 头，其后一行字段名、一行取值，时间单位为纳秒
+func readZFSPoolIO(path string) (disk.IOCountersStat, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return disk.IOCountersStat{}, false
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) < 3 {
+		return disk.IOCountersStat{}, false
+	}
+	names, values := strings.Fields(lines[1]), strings.Fields(lines[2])
+	if len(names) != len(values) {
+		return disk.IOCountersStat{}, false
+	}
+	fields := make(map[string]uint64, len(names))
+	for i, name := range names {
+		v, err := strconv.ParseUint(values[i], 10, 64)
+		if err != nil {
+			return disk.IOCountersStat{}, false
+		}
+		fields[name] = v
+	}
+	return disk.IOCountersStat{
+		ReadCount:  fields["reads"],
+		WriteCount: fields["writes"],
+		ReadBytes:  fields["nread"],
+		WriteBytes: fields["nwritten"],
+		IoTime:     fields["rtime"] / 1e6,
+	}, true
+}
+
+// readZFSKstatNamed 解析 "name type data" 格式的命名 kstat，跳过非数值字段
+func readZFSKstatNamed(path string) map[string]uint64 {
+	stats := make(map[string]uint64)
+	file, err := os.Open(path)
+	if err != nil {
+		return stats
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[2], 10, 64); err == nil {
+			stats[fields[0]] = v
+		}
+	}
+	return stats
+}
@@ -0,0 +1,50 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+func TestReadZFSPoolCounters(t *testing.T) {
+	root := t.TempDir()
+	// 旧版 OpenZFS 的 pool 级 io kstat
+	writeSysFile(t, root, "legacy/io", `12 3 0x00 1 80 1234 5678
+nread    nwritten reads    writes   wtime    wlentime wupdate  rtime      rlentime rupdate  wcnt     rcnt
+4096     8192     4        8        100      200      300      2500000000 400      500      0        0`)
+	// OpenZFS 2.1 起只有数据集级 objset kstat
+	writeSysFile(t, root, "tank/objset-0x36", `47 1 0x01 7 2160 5736297440 6398406826474
+name                            type data
+dataset_name                    7    tank
+writes                          4    10
+nwritten                        4    1000
+reads                           4    20
+nread                           4    2000
+nunlinks                        4    0
+nunlinked                       4    0`)
+	writeSysFile(t, root, "tank/objset-0x102", `47 1 0x01 7 2160 5736297440 6398406826474
+name                            type data
+dataset_name                    7    tank/media
+writes                          4    5
+nwritten                        4    500
+reads                           4    1
+nread                           4    100
+nunlinks                        4    0
+nunlinked                       4    0`)
+
+	got := readZFSPoolCounters(root, []string{"legacy", "tank", "missing"})
+	want := map[string]disk.IOCountersStat{
+		"legacy": {Name: "legacy", ReadBytes: 4096, WriteBytes: 8192, ReadCount: 4, WriteCount: 8, IoTime: 2500},
+		"tank":   {Name: "tank", ReadBytes: 2100, WriteBytes: 1500, ReadCount: 21, WriteCount: 15},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("counters = %+v, want %+v", got, want)
+	}
+	for name, c := range want {
+		if got[name] != c {
+			t.Errorf("pool %s = %+v, want %+v", name, got[name], c)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package monitoring
+
+import "github.com/shirou/gopsutil/v4/disk"
+
+// zfsPoolCounters 其他平台暂不读取 ZFS pool 的 I/O 计数器
+func zfsPoolCounters(pools []string) map[string]disk.IOCountersStat {
+	return nil
+}
@@ -0,0 +1,81 @@
+package monitoring
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+func TestSelectIODevices(t *testing.T) {
+	parts := []disk.PartitionStat{
+		{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+		{Device: "/dev/sda2", Mountpoint: "/data/sub", Fstype: "btrfs"},
+		{Device: "/dev/sda2", Mountpoint: "/data", Fstype: "btrfs"},
+		{Device: "/dev/mapper/vg-home", Mountpoint: "/home", Fstype: "xfs"},
+		{Device: "tank/media", Mountpoint: "/tank/media", Fstype: "zfs"},
+		{Device: "tank", Mountpoint: "/tank", Fstype: "zfs"},
+		{Device: "backup/daily", Mountpoint: "/backup/daily", Fstype: "zfs"},
+		{Device: "tmpfs", Mountpoint: "/run", Fstype: "tmpfs"},
+		{Device: "/dev/loop0", Mountpoint: "/snap/core", Fstype: "squashfs"},
+	}
+	counters := map[string]disk.IOCountersStat{
+		"sda1":  {Name: "sda1"},
+		"sda2":  {Name: "sda2"},
+		"dm-0":  {Name: "dm-0", Label: "vg-home"},
+		"loop0": {Name: "loop0"},
+		// ZFS pool 的计数器由 addZFSPoolCounters 加入，backup 没有可用的 kstat
+		"tank": {Name: "tank"},
+	}
+
+	got := selectIODevices(parts, nil, counters)
+	want := map[string]string{"sda1": "/", "sda2": "/data", "dm-0": "/home", "tank": "/tank"}
+	if len(got) != len(want) {
+		t.Fatalf("devices = %v, want %v", got, want)
+	}
+	for name, mountpoint := range want {
+		if got[name] != mountpoint {
+			t.Errorf("device %s mountpoint = %q, want %q", name, got[name], mountpoint)
+		}
+	}
+
+	got = selectIODevices(parts, []string{"/home", "/run"}, counters)
+	if len(got) != 1 || got["dm-0"] != "/home" {
+		t.Errorf("devices with include list = %v, want only dm-0", got)
+	}
+}
+
+func TestDiskIORates(t *testing.T) {
+	devices := map[string]string{"sda": "/", "sdb": "/data", "sdc": "/new"}
+	prev := map[string]disk.IOCountersStat{
+		"sda": {ReadBytes: 1000, WriteBytes: 0, ReadCount: 10, WriteCount: 0, ReadTime: 10, IoTime: 100},
+		"sdb": {ReadBytes: 5000},
+	}
+	cur := map[string]disk.IOCountersStat{
+		"sda": {ReadBytes: 5000, WriteBytes: 2000, ReadCount: 30, WriteCount: 20, ReadTime: 90, WriteTime: 80, IoTime: 600},
+		// 计数器回绕
+		"sdb": {ReadBytes: 100},
+		"sdc": {ReadBytes: 100},
+	}
+
+	got := diskIORates(devices, prev, cur, 2*time.Second)
+	if len(got) != 1 {
+		t.Fatalf("rates = %+v, want only sda", got)
+	}
+	r := got[0]
+	if r.Device != "sda" || r.Mountpoint != "/" || r.ReadBytes != 2000 || r.WriteBytes != 1000 {
+		t.Errorf("unexpected throughput: %+v", r)
+	}
+	if r.ReadIOPS != 10 || r.WriteIOPS != 10 {
+		t.Errorf("iops = %v/%v, want 10/10", r.ReadIOPS, r.WriteIOPS)
+	}
+	// (80 + 80) ms / 40 次
+	if math.Abs(r.Await-4) > 1e-9 {
+		t.Errorf("await = %v, want 4", r.Await)
+	}
+	// 500 ms / 2000 ms
+	if math.Abs(r.Util-25) > 1e-9 {
+		t.Errorf("util = %v, want 25", r.Util)
+	}
+}
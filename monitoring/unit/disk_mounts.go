@@ -0,0 +1,100 @@
+package monitoring
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// MountInfo 单个挂载点的容量与 inode 使用情况
+type MountInfo struct {
+	Mountpoint  string `json:"mountpoint"`
+	Device      string `json:"device"`
+	Fstype      string `json:"fstype"`
+	Total       uint64 `json:"total"`
+	Used        uint64 `json:"used"`
+	Free        uint64 `json:"free"`
+	InodesTotal uint64 `json:"inodes_total"` // 不支持 inode 的文件系统（如 Windows、btrfs）为 0
+	InodesUsed  uint64 `json:"inodes_used"`
+	ReadOnly    bool   `json:"read_only"`
+}
+
+// Mountpoints 按与 Disk() 相同的物理磁盘规则返回各挂载点的使用情况。
+// 与 Disk() 汇总容量时按设备去重不同，这里列出每个不同的挂载点（如 btrfs 子卷、ZFS 数据集），
+// 只合并指向同一设备同一路径的 bind mount
+func Mountpoints() ([]MountInfo, error) {
+	parts, err := disk.Partitions(true)
+	if err != nil {
+		return nil, err
+	}
+	var result []MountInfo
+	for _, part := range selectMountpoints(parts, includedMountpoints(), mountRoots()) {
+		u, err := disk.Usage(part.Mountpoint)
+		if err != nil {
+			continue
+		}
+		result = append(result, MountInfo{
+			Mountpoint:  part.Mountpoint,
+			Device:      part.Device,
+			Fstype:      part.Fstype,
+			Total:       u.Total,
+			Used:        u.Used,
+			Free:        u.Free,
+			InodesTotal: u.InodesTotal,
+			InodesUsed:  u.InodesUsed,
+			ReadOnly:    isReadOnlyMount(part),
+		})
+	}
+	return result, nil
+}
+
+// selectMountpoints 选择要上报的挂载点：指定了 --include-mountpoint 时只取其中的挂载点，否则取物理磁盘。
+// roots 为挂载点到 "设备号 根路径" 的映射，相同的挂载视为 bind mount，只保留路径最短的挂载点；
+// 不在 roots 中的挂载点单独列出。结果按挂载点排序。
+func selectMountpoints(parts []disk.PartitionStat, includeMounts []string, roots map[string]string) []disk.PartitionStat {
+	var selected []disk.PartitionStat
+	if len(includeMounts) > 0 {
+		byMountpoint := make(map[string]disk.PartitionStat)
+		for _, part := range parts {
+			byMountpoint[part.Mountpoint] = part
+		}
+		for _, mountpoint := range includeMounts {
+			part, ok := byMountpoint[mountpoint]
+			if !ok {
+				// 未出现在分区列表中的挂载点仍按路径统计
+				part = disk.PartitionStat{Mountpoint: mountpoint}
+			}
+			selected = append(selected, part)
+		}
+	} else {
+		mounts := make(map[string]disk.PartitionStat)
+		for _, part := range parts {
+			if !isPhysicalDisk(part) {
+				continue
+			}
+			key := "mountpoint " + part.Mountpoint
+			if root, ok := roots[part.Mountpoint]; ok {
+				key = "root " + root
+			}
+			if existing, ok := mounts[key]; !ok || len(part.Mountpoint) < len(existing.Mountpoint) {
+				mounts[key] = part
+			}
+		}
+		for _, part := range mounts {
+			selected = append(selected, part)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Mountpoint < selected[j].Mountpoint })
+	return selected
+}
+
+// isReadOnlyMount 根据挂载选项判断是否只读，gopsutil 在各平台都以 "ro" 表示只读
+func isReadOnlyMount(part disk.PartitionStat) bool {
+	for _, opt := range part.Opts {
+		if strings.TrimSpace(opt) == "ro" {
+			return true
+		}
+	}
+	return false
+}
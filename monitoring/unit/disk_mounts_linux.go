@@ -0,0 +1,61 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// mountRoots 从 mountinfo 读取各挂载点在其文件系统中的根路径，用于识别 bind mount。
+// 与 gopsutil 一致优先读取 1 号进程的挂载表
+func mountRoots() map[string]string {
+	for _, pid := range []string{"1", "self"} {
+		if roots, err := readMountRoots(filepath.Join(procRoot(), pid, "mountinfo")); err == nil {
+			return roots
+		}
+	}
+	return nil
+}
+
+// readMountRoots 解析 mountinfo，返回挂载点到 "主:次设备号 根路径" 的映射
+func readMountRoots(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	roots := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		roots[unescapeMountPath(fields[4])] = fields[2] + " " + unescapeMountPath(fields[3])
+	}
+	return roots, scanner.Err()
+}
+
+// unescapeMountPath 还原 mountinfo 中以 \040 等八进制形式转义的空白与反斜杠
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,35 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReadMountRoots(t *testing.T) {
+	root := t.TempDir()
+	writeSysFile(t, root, "mountinfo", `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+30 22 0:40 /@data /data rw,relatime shared:2 - btrfs /dev/sdb1 rw,subvol=/@data
+31 22 0:40 /@data /srv/my\040data rw,relatime shared:2 - btrfs /dev/sdb1 rw,subvol=/@data
+32 22 0:51 / /tank/media rw,xattr - zfs tank/media rw`)
+
+	roots, err := readMountRoots(filepath.Join(root, "mountinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"/":            "8:1 /",
+		"/data":        "0:40 /@data",
+		"/srv/my data": "0:40 /@data",
+		"/tank/media":  "0:51 /",
+	}
+	if len(roots) != len(want) {
+		t.Fatalf("roots = %v, want %v", roots, want)
+	}
+	for mountpoint, r := range want {
+		if roots[mountpoint] != r {
+			t.Errorf("root of %q = %q, want %q", mountpoint, roots[mountpoint], r)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package monitoring
+
+// mountRoots 其他平台无法识别 bind mount，每个挂载点都单独列出
+func mountRoots() map[string]string {
+	return nil
+}
@@ -0,0 +1,60 @@
+package monitoring
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+func TestSelectMountpoints(t *testing.T) {
+	parts := []disk.PartitionStat{
+		{Device: "/dev/sda2", Mountpoint: "/var", Fstype: "ext4", Opts: []string{"rw"}},
+		{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4", Opts: []string{"rw"}},
+		{Device: "/dev/sdb1", Mountpoint: "/data/sub", Fstype: "btrfs"},
+		{Device: "/dev/sdb1", Mountpoint: "/data", Fstype: "btrfs"},
+		{Device: "/dev/sdb1", Mountpoint: "/srv/data", Fstype: "btrfs"},
+		{Device: "tank/media", Mountpoint: "/tank/media", Fstype: "zfs"},
+		{Device: "tank", Mountpoint: "/tank", Fstype: "zfs"},
+		{Device: "tmpfs", Mountpoint: "/run", Fstype: "tmpfs"},
+	}
+	roots := map[string]string{
+		"/":           "8:1 /",
+		"/var":        "8:2 /",
+		"/data":       "0:40 /@data",
+		"/data/sub":   "0:40 /@sub",
+		"/srv/data":   "0:40 /@data", // /data 的 bind mount
+		"/tank":       "0:50 /",
+		"/tank/media": "0:51 /",
+	}
+
+	// btrfs 子卷与 ZFS 数据集分别列出，bind mount 只保留路径最短的一个
+	got := selectMountpoints(parts, nil, roots)
+	want := []string{"/", "/data", "/data/sub", "/tank", "/tank/media", "/var"}
+	if len(got) != len(want) {
+		t.Fatalf("selected = %+v, want mountpoints %v", got, want)
+	}
+	for i, mountpoint := range want {
+		if got[i].Mountpoint != mountpoint {
+			t.Errorf("selected[%d] = %q, want %q", i, got[i].Mountpoint, mountpoint)
+		}
+	}
+
+	// 无法读取挂载信息时每个挂载点都单独列出
+	if got := selectMountpoints(parts, nil, nil); len(got) != 7 {
+		t.Errorf("selected without roots = %+v, want 7 mountpoints", got)
+	}
+
+	got = selectMountpoints(parts, []string{"/var", "/mnt/extra"}, roots)
+	if len(got) != 2 || got[0].Mountpoint != "/mnt/extra" || got[1].Device != "/dev/sda2" {
+		t.Errorf("selected with include list = %+v", got)
+	}
+}
+
+func TestIsReadOnlyMount(t *testing.T) {
+	if !isReadOnlyMount(disk.PartitionStat{Opts: []string{"ro", "relatime"}}) {
+		t.Error("expected ro mount to be read-only")
+	}
+	if isReadOnlyMount(disk.PartitionStat{Opts: []string{"rw", "errors=remount-ro"}}) {
+		t.Error("expected rw mount not to be read-only")
+	}
+}
@@ -32,11 +32,20 @@ type ProcMemInfo struct {
 	SReclaimable uint64
 	Zswap        uint64
 	Zswapped     uint64
+	Dirty        uint64
+	// HugePages_Total/HugePages_Free 为页数而不是 kB
+	HugePagesTotal uint64
+	HugePagesFree  uint64
+	HugePageSize   uint64
 }
 
 // readProcMeminfo reads /proc/meminfo and returns a filled ProcMemInfo struct
 func ReadProcMeminfo() (*ProcMemInfo, error) {
-	file, err := os.Open("/proc/meminfo")
+	return readProcMeminfoFrom("/proc/meminfo")
+}
+
+func readProcMeminfoFrom(path string) (*ProcMemInfo, error) {
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
@@ -57,6 +66,14 @@ func ReadProcMeminfo() (*ProcMemInfo, error) {
 		if err != nil {
 			continue
 		}
+		switch key {
+		case "HugePages_Total":
+			info.HugePagesTotal = val
+			continue
+		case "HugePages_Free":
+			info.HugePagesFree = val
+			continue
+		}
 		val *= 1024 // Convert kB to bytes
 
 		switch key {
@@ -84,6 +101,10 @@ func ReadProcMeminfo() (*ProcMemInfo, error) {
 			info.Zswap = val
 		case "Zswapped":
 			info.Zswapped = val
+		case "Dirty":
+			info.Dirty = val
+		case "Hugepagesize":
+			info.HugePageSize = val
 		}
 	}
 	return info, scanner.Err()
@@ -181,7 +202,20 @@ func CallFree() RamInfo {
 	return raminfo
 }
 
-func Ram() RamInfo {
+// Ram 返回内存用量，cg 为本次读取的 cgroup 数据，不在容器中时为 nil
+func Ram(cg *CgroupSnapshot) RamInfo {
+	// 容器中以 cgroup 的内存上限与用量为准
+	if cg != nil {
+		raminfo := RamInfo{Total: cg.stats.memLimit, Used: cgroupMemoryUsed(cg.stats), Mode: "cgroup"}
+		if raminfo.Total == 0 {
+			// 未限制内存时总量取宿主机内存
+			if v, err := mem.VirtualMemory(); err == nil {
+				raminfo.Total = v.Total
+			}
+		}
+		return raminfo
+	}
+
 	// Use global config
 	if pkg_flags.GlobalConfig.MemoryIncludeCache {
 		v, err := mem.VirtualMemory()
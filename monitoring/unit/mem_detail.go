@@ -0,0 +1,76 @@
+package monitoring
+
+import (
+	"runtime"
+
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// MemDetail 内存构成明细，单位均为字节（大页数量除外）。
+// cgroup 模式下取自容器的 memory.stat，不适用于容器的宿主机级别字段留空
+type MemDetail struct {
+	Mode           string `json:"mode"`                // 计算 ram.used 使用的方式：cgroup、includeCache、htoplike 或 gopsutil
+	Available      uint64 `json:"available,omitempty"` // cgroup 模式下为上限减去已用，未限制时留空
+	Free           uint64 `json:"free,omitempty"`
+	Cached         uint64 `json:"cached"`
+	Buffers        uint64 `json:"buffers,omitempty"`
+	Shared         uint64 `json:"shared"`
+	SReclaimable   uint64 `json:"sreclaimable,omitempty"`
+	Dirty          uint64 `json:"dirty"`
+	Anon           uint64 `json:"anon,omitempty"`            // 仅 cgroup 模式：匿名内存
+	InactiveFile   uint64 `json:"inactive_file,omitempty"`   // 仅 cgroup 模式：不活跃的文件缓存
+	HugePagesTotal uint64 `json:"hugepages_total,omitempty"` // 大页数量
+	HugePagesFree  uint64 `json:"hugepages_free,omitempty"`
+	HugePageSize   uint64 `json:"hugepage_size,omitempty"`
+	Zswap          uint64 `json:"zswap,omitempty"`    // zswap 压缩后占用的内存
+	Zswapped       uint64 `json:"zswapped,omitempty"` // 被 zswap 压缩的原始数据量
+}
+
+// MemoryDetail 返回内存明细，mode 为 Ram 计算 used 时使用的方式，cg 为同一次读取的 cgroup 数据。
+// cgroup 模式下使用容器的 memory.stat，避免混入宿主机的 /proc/meminfo
+func MemoryDetail(mode string, cg *CgroupSnapshot) MemDetail {
+	if mode == "cgroup" {
+		if cg != nil {
+			return cgroupMemDetail(cg.stats)
+		}
+		return MemDetail{Mode: mode}
+	}
+	detail := MemDetail{Mode: mode}
+	if runtime.GOOS == "linux" {
+		if info, err := ReadProcMeminfo(); err == nil && info.MemTotal > 0 {
+			fillMemDetail(&detail, info)
+			return detail
+		}
+	}
+
+	v, err := mem.VirtualMemory()
+	if err != nil {
+		return detail
+	}
+	detail.Available = v.Available
+	detail.Free = v.Free
+	detail.Cached = v.Cached
+	detail.Buffers = v.Buffers
+	detail.Shared = v.Shared
+	detail.SReclaimable = v.Sreclaimable
+	detail.Dirty = v.Dirty
+	detail.HugePagesTotal = v.HugePagesTotal
+	detail.HugePagesFree = v.HugePagesFree
+	detail.HugePageSize = v.HugePageSize
+	return detail
+}
+
+func fillMemDetail(detail *MemDetail, info *ProcMemInfo) {
+	detail.Available = info.MemAvailable
+	detail.Free = info.MemFree
+	detail.Cached = info.Cached
+	detail.Buffers = info.Buffers
+	detail.Shared = info.Shmem
+	detail.SReclaimable = info.SReclaimable
+	detail.Dirty = info.Dirty
+	detail.HugePagesTotal = info.HugePagesTotal
+	detail.HugePagesFree = info.HugePagesFree
+	detail.HugePageSize = info.HugePageSize
+	detail.Zswap = info.Zswap
+	detail.Zswapped = info.Zswapped
+}
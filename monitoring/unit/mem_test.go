@@ -0,0 +1,54 @@
+package monitoring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadProcMeminfoDetail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meminfo")
+	content := `MemTotal:        8000000 kB
+MemFree:         1000000 kB
+MemAvailable:    5000000 kB
+Buffers:          200000 kB
+Cached:          3000000 kB
+Shmem:            100000 kB
+SReclaimable:     300000 kB
+Dirty:              1234 kB
+Zswap:             50000 kB
+Zswapped:         150000 kB
+HugePages_Total:      16
+HugePages_Free:        4
+Hugepagesize:       2048 kB
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := readProcMeminfoFrom(path)
+	if err != nil {
+		t.Fatalf("readProcMeminfoFrom failed: %v", err)
+	}
+	detail := MemDetail{Mode: "htoplike"}
+	fillMemDetail(&detail, info)
+
+	want := MemDetail{
+		Mode:           "htoplike",
+		Available:      5000000 << 10,
+		Free:           1000000 << 10,
+		Cached:         3000000 << 10,
+		Buffers:        200000 << 10,
+		Shared:         100000 << 10,
+		SReclaimable:   300000 << 10,
+		Dirty:          1234 << 10,
+		HugePagesTotal: 16,
+		HugePagesFree:  4,
+		HugePageSize:   2048 << 10,
+		Zswap:          50000 << 10,
+		Zswapped:       150000 << 10,
+	}
+	if detail != want {
+		t.Errorf("detail = %+v\nwant     %+v", detail, want)
+	}
+}
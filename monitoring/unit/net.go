@@ -3,6 +3,7 @@ package monitoring
 import (
 	"bufio"
 	"fmt"
+	stdnet "net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -15,39 +16,77 @@ import (
 	"github.com/shirou/gopsutil/v4/net"
 )
 
+// ConnectionStats 连接统计
+type ConnectionStats struct {
+	TCP         int
+	UDP         int
+	TCPStates   map[string]map[string]int // 地址族 (ipv4/ipv6) -> TCP 状态 -> 数量
+	Listen      int                       // 处于 LISTEN 状态的 TCP 套接字数量
+	RemotePeers int                       // TCP 连接的不同远端地址数量
+}
+
 func ConnectionsCount() (tcpCount, udpCount int, err error) {
+	stats, err := Connections()
+	return stats.TCP, stats.UDP, err
+}
+
+// Connections 返回 TCP/UDP 连接数与 TCP 状态分布
+func Connections() (ConnectionStats, error) {
 	if runtime.GOOS == "linux" {
-		return connectionsCountWithProcFallback(procRoot(), gopsutilConnectionsCount)
+		return connectionsWithProcFallback(procRoot(), gopsutilConnections)
 	}
 
-	return gopsutilConnectionsCount()
+	return gopsutilConnections()
 }
 
-func connectionsCountWithProcFallback(root string, fallback func() (int, int, error)) (tcpCount, udpCount int, err error) {
-	var procErr error
-	tcpCount, udpCount, procErr = procNetConnectionsCount(root)
+func connectionsWithProcFallback(root string, fallback func() (ConnectionStats, error)) (ConnectionStats, error) {
+	stats, procErr := procNetConnections(root)
 	if procErr == nil {
-		return tcpCount, udpCount, nil
+		return stats, nil
 	}
 
-	tcpCount, udpCount, err = fallback()
+	stats, err := fallback()
 	if err != nil && procErr != nil {
-		return 0, 0, fmt.Errorf("proc net fast path failed: %w; gopsutil fallback failed: %w", procErr, err)
+		return ConnectionStats{}, fmt.Errorf("proc net fast path failed: %w; gopsutil fallback failed: %w", procErr, err)
 	}
-	return tcpCount, udpCount, err
+	return stats, err
 }
 
-func gopsutilConnectionsCount() (tcpCount, udpCount int, err error) {
+func gopsutilConnections() (ConnectionStats, error) {
 	tcps, err := net.Connections("tcp")
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to get TCP connections: %w", err)
+		return ConnectionStats{}, fmt.Errorf("failed to get TCP connections: %w", err)
 	}
 	udps, err := net.Connections("udp")
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to get UDP connections: %w", err)
+		return ConnectionStats{}, fmt.Errorf("failed to get UDP connections: %w", err)
+	}
+
+	stats := ConnectionStats{TCP: len(tcps), UDP: len(udps), TCPStates: make(map[string]map[string]int)}
+	peers := make(map[string]struct{})
+	for _, conn := range tcps {
+		family := "ipv4"
+		if strings.Contains(conn.Laddr.IP, ":") {
+			family = "ipv6"
+		}
+		addTCPState(stats.TCPStates, family, conn.Status)
+		if conn.Status == "LISTEN" {
+			stats.Listen++
+		}
+		// IPv4 映射地址解析后与 IPv4 地址相同，避免重复计数
+		if ip := stdnet.ParseIP(conn.Raddr.IP); ip != nil && !ip.IsUnspecified() {
+			peers[ip.String()] = struct{}{}
+		}
 	}
+	stats.RemotePeers = len(peers)
+	return stats, nil
+}
 
-	return len(tcps), len(udps), nil
+func addTCPState(states map[string]map[string]int, family, state string) {
+	if states[family] == nil {
+		states[family] = make(map[string]int)
+	}
+	states[family][state]++
 }
 
 func procRoot() string {
@@ -57,46 +96,93 @@ func procRoot() string {
 	return "/proc"
 }
 
-func procNetConnectionsCount(root string) (tcpCount, udpCount int, err error) {
-	tcpCount, err = countProcNetFiles(root, "tcp", "tcp6")
+func procNetConnections(root string) (ConnectionStats, error) {
+	tcp, err := countProcNetFiles(root, "tcp", "tcp6")
 	if err != nil {
-		return 0, 0, err
+		return ConnectionStats{}, err
 	}
-	udpCount, err = countProcNetFiles(root, "udp", "udp6")
+	udp, err := countProcNetFiles(root, "udp", "udp6")
 	if err != nil {
-		return 0, 0, err
+		return ConnectionStats{}, err
+	}
+
+	stats := ConnectionStats{TCPStates: make(map[string]map[string]int)}
+	peers := make(map[string]struct{})
+	for name, file := range tcp {
+		family := "ipv4"
+		if name == "tcp6" {
+			family = "ipv6"
+		}
+		stats.TCP += file.count
+		for state, n := range file.states {
+			if stats.TCPStates[family] == nil {
+				stats.TCPStates[family] = make(map[string]int)
+			}
+			stats.TCPStates[family][state] += n
+		}
+		stats.Listen += file.states["LISTEN"]
+		for peer := range file.peers {
+			peers[peer] = struct{}{}
+		}
+	}
+	for _, file := range udp {
+		stats.UDP += file.count
 	}
-	return tcpCount, udpCount, nil
+	stats.RemotePeers = len(peers)
+	return stats, nil
 }
 
-func countProcNetFiles(root string, names ...string) (int, error) {
-	total := 0
-	readAny := false
+// countProcNetFiles 读取 root/net 下的多个文件，返回按文件名索引的统计结果
+func countProcNetFiles(root string, names ...string) (map[string]procNetFileStats, error) {
+	result := make(map[string]procNetFileStats)
 	for _, name := range names {
-		count, err := countProcNetFile(filepath.Join(root, "net", name))
+		stats, err := countProcNetFile(filepath.Join(root, "net", name))
 		if err != nil {
 			if os.IsNotExist(err) {
 				continue
 			}
-			return 0, err
+			return nil, err
 		}
-		total += count
-		readAny = true
+		result[name] = stats
 	}
-	if !readAny {
-		return 0, fmt.Errorf("no proc net files found under %s", filepath.Join(root, "net"))
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no proc net files found under %s", filepath.Join(root, "net"))
 	}
-	return total, nil
+	return result, nil
 }
 
-func countProcNetFile(path string) (int, error) {
+// procNetStates /proc/net/tcp 中 st 列的十六进制状态码，见内核 include/net/tcp_states.h
+var procNetStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+	"0C": "NEW_SYN_RECV",
+}
+
+// procNetFileStats 单个 /proc/net 文件的统计结果
+type procNetFileStats struct {
+	count  int
+	states map[string]int      // 状态名 -> 数量
+	peers  map[string]struct{} // 非零的远端地址（不含端口）
+}
+
+// countProcNetFile 统计 /proc/net/{tcp,udp}[6] 中的套接字数量、状态分布与远端地址
+func countProcNetFile(path string) (procNetFileStats, error) {
+	stats := procNetFileStats{states: make(map[string]int), peers: make(map[string]struct{})}
 	file, err := os.Open(path)
 	if err != nil {
-		return 0, err
+		return stats, err
 	}
 	defer file.Close()
 
-	count := 0
 	scanner := bufio.NewScanner(file)
 	header := true
 	for scanner.Scan() {
@@ -104,11 +190,47 @@ func countProcNetFile(path string) (int, error) {
 			header = false
 			continue
 		}
-		if strings.TrimSpace(scanner.Text()) != "" {
-			count++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		stats.count++
+		// 字段依次为 sl local_address rem_address st ...
+		if len(fields) < 4 {
+			continue
+		}
+		state, ok := procNetStates[strings.ToUpper(fields[3])]
+		if !ok {
+			state = "UNKNOWN"
+		}
+		stats.states[state]++
+		if state == "LISTEN" {
+			continue
+		}
+		if peer := procNetPeer(fields[2]); peer != "" {
+			stats.peers[peer] = struct{}{}
+		}
+	}
+	return stats, scanner.Err()
+}
+
+// procNetPeer 从 "地址:端口" 形式的十六进制远端地址中取出地址部分，全零地址返回空字符串。
+// tcp6 中的 IPv4 映射地址转换为与 tcp 相同的 8 位形式，以便去重。
+func procNetPeer(remote string) string {
+	addr, _, ok := strings.Cut(remote, ":")
+	if !ok || strings.Trim(addr, "0") == "" {
+		return ""
+	}
+	addr = strings.ToUpper(addr)
+	if len(addr) == 32 {
+		// ::ffff:a.b.c.d 在小端与大端主机上的表示
+		for _, prefix := range []string{"0000000000000000FFFF0000", "00000000000000000000FFFF"} {
+			if strings.HasPrefix(addr, prefix) {
+				return addr[len(prefix):]
+			}
 		}
 	}
-	return count, scanner.Err()
+	return addr
 }
 
 var (
@@ -0,0 +1,110 @@
+package monitoring
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/komari-monitor/komari-agent/monitoring/netstatic"
+	"github.com/komari-monitor/komari-agent/utils"
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// NicInfo 单个网卡的流量统计
+type NicInfo struct {
+	Name        string  `json:"name"`
+	Up          uint64  `json:"up"`        // 每秒发送字节数
+	Down        uint64  `json:"down"`      // 每秒接收字节数
+	TotalUp     uint64  `json:"totalUp"`   // 启用月重置时为本周期内的发送量
+	TotalDown   uint64  `json:"totalDown"` // 启用月重置时为本周期内的接收量
+	PacketsUp   float64 `json:"packets_up"`
+	PacketsDown float64 `json:"packets_down"`
+	ErrorsIn    uint64  `json:"errors_in"` // 以下为开机以来的累计值
+	ErrorsOut   uint64  `json:"errors_out"`
+	DropsIn     uint64  `json:"drops_in"`
+	DropsOut    uint64  `json:"drops_out"`
+	Speed       int64   `json:"speed,omitempty"`     // 链路速率，单位 Mbps，未知时省略
+	OperState   string  `json:"operstate,omitempty"` // 链路状态，如 up、down、unknown
+}
+
+// nicLink 网卡的链路信息
+type nicLink struct {
+	speed     int64
+	operState string
+}
+
+// nicSampleState 上一次各网卡的计数器采样，用于计算速率
+var nicSampleState struct {
+	sync.Mutex
+	counters map[string]net.IOCountersStat
+	at       time.Time
+}
+
+// Nics 返回按 --include-nics/--exclude-nics 过滤后的各网卡统计，首次调用时速率为 0
+func Nics() ([]NicInfo, error) {
+	includeNics := parseNics(flags.IncludeNics)
+	excludeNics := parseNics(flags.ExcludeNics)
+
+	ioCounters, err := net.IOCounters(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network IO counters: %w", err)
+	}
+	now := time.Now()
+	counters := make(map[string]net.IOCountersStat)
+	for _, c := range ioCounters {
+		if shouldInclude(c.Name, includeNics, excludeNics) {
+			counters[c.Name] = c
+		}
+	}
+
+	nicSampleState.Lock()
+	prev, elapsed := nicSampleState.counters, now.Sub(nicSampleState.at)
+	nicSampleState.counters, nicSampleState.at = counters, now
+	nicSampleState.Unlock()
+
+	nics := buildNicInfo(counters, prev, elapsed, readNicLinks(counters))
+
+	// 与 NetworkSpeed 一致，启用月重置时总量取自 netstatic，失败时保留计数器累计值
+	if flags.MonthRotate != 0 {
+		netstatic.StartOrContinue()
+		resetDay := uint64(utils.GetLastResetDate(flags.MonthRotate, now).Unix())
+		totals, err := netstatic.GetTotalTrafficBetween(resetDay, uint64(now.Unix()))
+		if err != nil {
+			return nics, fmt.Errorf("failed to call GetTotalTrafficBetween: %w", err)
+		}
+		for i := range nics {
+			t := totals[nics[i].Name]
+			nics[i].TotalUp, nics[i].TotalDown = t.Tx, t.Rx
+		}
+	}
+	return nics, nil
+}
+
+// buildNicInfo 根据两次计数器采样计算各网卡的速率，prev 中没有的网卡速率为 0
+func buildNicInfo(cur, prev map[string]net.IOCountersStat, elapsed time.Duration, links map[string]nicLink) []NicInfo {
+	seconds := elapsed.Seconds()
+	nics := make([]NicInfo, 0, len(cur))
+	for name, c := range cur {
+		nic := NicInfo{
+			Name:      name,
+			TotalUp:   c.BytesSent,
+			TotalDown: c.BytesRecv,
+			ErrorsIn:  c.Errin,
+			ErrorsOut: c.Errout,
+			DropsIn:   c.Dropin,
+			DropsOut:  c.Dropout,
+			Speed:     links[name].speed,
+			OperState: links[name].operState,
+		}
+		if p, ok := prev[name]; ok && seconds > 0 {
+			nic.Up = uint64(float64(safeCounterDelta(c.BytesSent, p.BytesSent)) / seconds)
+			nic.Down = uint64(float64(safeCounterDelta(c.BytesRecv, p.BytesRecv)) / seconds)
+			nic.PacketsUp = float64(safeCounterDelta(c.PacketsSent, p.PacketsSent)) / seconds
+			nic.PacketsDown = float64(safeCounterDelta(c.PacketsRecv, p.PacketsRecv)) / seconds
+		}
+		nics = append(nics, nic)
+	}
+	sort.Slice(nics, func(i, j int) bool { return nics[i].Name < nics[j].Name })
+	return nics
+}
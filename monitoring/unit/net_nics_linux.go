@@ -0,0 +1,28 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"path/filepath"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// readNicLinks 从 /sys/class/net 读取链路速率与状态
+func readNicLinks(counters map[string]net.IOCountersStat) map[string]nicLink {
+	return readNicLinksFrom(sysRoot(), counters)
+}
+
+func readNicLinksFrom(root string, counters map[string]net.IOCountersStat) map[string]nicLink {
+	links := make(map[string]nicLink, len(counters))
+	for name := range counters {
+		dir := filepath.Join(root, "class", "net", name)
+		link := nicLink{operState: readSysString(filepath.Join(dir, "operstate"))}
+		// 链路断开或虚拟网卡的 speed 读取失败或为 -1
+		if speed, ok := readSysFloat(filepath.Join(dir, "speed")); ok && speed > 0 {
+			link.speed = int64(speed)
+		}
+		links[name] = link
+	}
+	return links
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+func TestReadNicLinksFrom(t *testing.T) {
+	root := t.TempDir()
+	writeSysFile(t, root, "class/net/eth0/speed", "1000")
+	writeSysFile(t, root, "class/net/eth0/operstate", "up")
+	writeSysFile(t, root, "class/net/wg0/speed", "-1")
+	writeSysFile(t, root, "class/net/wg0/operstate", "unknown")
+
+	links := readNicLinksFrom(root, map[string]net.IOCountersStat{"eth0": {}, "wg0": {}, "gone0": {}})
+	if links["eth0"] != (nicLink{speed: 1000, operState: "up"}) {
+		t.Errorf("eth0 = %+v", links["eth0"])
+	}
+	if links["wg0"] != (nicLink{operState: "unknown"}) {
+		t.Errorf("wg0 = %+v", links["wg0"])
+	}
+	if links["gone0"] != (nicLink{}) {
+		t.Errorf("gone0 = %+v", links["gone0"])
+	}
+}
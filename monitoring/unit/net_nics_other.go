@@ -0,0 +1,30 @@
+//go:build !linux
+
+package monitoring
+
+import (
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// readNicLinks 非 Linux 平台无法获取链路速率，仅根据网卡标志判断状态
+func readNicLinks(counters map[string]net.IOCountersStat) map[string]nicLink {
+	links := make(map[string]nicLink, len(counters))
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return links
+	}
+	for _, iface := range interfaces {
+		if _, ok := counters[iface.Name]; !ok {
+			continue
+		}
+		state := "down"
+		for _, flag := range iface.Flags {
+			if flag == "up" {
+				state = "up"
+				break
+			}
+		}
+		links[iface.Name] = nicLink{operState: state}
+	}
+	return links
+}
@@ -0,0 +1,43 @@
+package monitoring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+func TestBuildNicInfo(t *testing.T) {
+	prev := map[string]net.IOCountersStat{
+		"eth0": {Name: "eth0", BytesSent: 1000, BytesRecv: 4000, PacketsSent: 10, PacketsRecv: 20},
+		"wg0":  {Name: "wg0", BytesSent: 500, BytesRecv: 500},
+	}
+	cur := map[string]net.IOCountersStat{
+		"eth0": {Name: "eth0", BytesSent: 3000, BytesRecv: 8000, PacketsSent: 30, PacketsRecv: 60, Errin: 1, Dropout: 2},
+		// 计数器回绕时速率为 0
+		"wg0":  {Name: "wg0", BytesSent: 100, BytesRecv: 900},
+		"new0": {Name: "new0", BytesSent: 100},
+	}
+	links := map[string]nicLink{"eth0": {speed: 1000, operState: "up"}}
+
+	nics := buildNicInfo(cur, prev, 2*time.Second, links)
+	if len(nics) != 3 || nics[0].Name != "eth0" || nics[1].Name != "new0" || nics[2].Name != "wg0" {
+		t.Fatalf("unexpected nics: %+v", nics)
+	}
+	eth := nics[0]
+	if eth.Up != 1000 || eth.Down != 2000 || eth.PacketsUp != 10 || eth.PacketsDown != 20 {
+		t.Errorf("eth0 rates = %+v", eth)
+	}
+	if eth.TotalUp != 3000 || eth.TotalDown != 8000 || eth.ErrorsIn != 1 || eth.DropsOut != 2 {
+		t.Errorf("eth0 counters = %+v", eth)
+	}
+	if eth.Speed != 1000 || eth.OperState != "up" {
+		t.Errorf("eth0 link = %d %q", eth.Speed, eth.OperState)
+	}
+	if nics[1].Up != 0 || nics[1].TotalUp != 100 {
+		t.Errorf("new0 = %+v, want zero rate", nics[1])
+	}
+	if nics[2].Up != 0 || nics[2].Down != 200 {
+		t.Errorf("wg0 = %+v, want up 0 down 200", nics[2])
+	}
+}
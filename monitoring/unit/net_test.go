@@ -2,6 +2,8 @@ package monitoring
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -30,16 +32,16 @@ func TestConnectionsCountCombinesProcAndFallbackErrors(t *testing.T) {
 	}
 
 	fallbackErr := errors.New("fallback unavailable")
-	fallback := func() (int, int, error) {
-		return 0, 0, fallbackErr
+	fallback := func() (ConnectionStats, error) {
+		return ConnectionStats{}, fallbackErr
 	}
 
-	tcpCount, udpCount, err := connectionsCountWithProcFallback(t.TempDir(), fallback)
+	stats, err := connectionsWithProcFallback(t.TempDir(), fallback)
 	if err == nil {
 		t.Fatal("expected combined error, got nil")
 	}
-	if tcpCount != 0 || udpCount != 0 {
-		t.Fatalf("expected zero counts on failure, got tcp=%d udp=%d", tcpCount, udpCount)
+	if stats.TCP != 0 || stats.UDP != 0 {
+		t.Fatalf("expected zero counts on failure, got tcp=%d udp=%d", stats.TCP, stats.UDP)
 	}
 	if !errors.Is(err, fallbackErr) {
 		t.Fatalf("expected combined error to wrap fallback error, got %v", err)
@@ -53,6 +55,59 @@ func TestConnectionsCountCombinesProcAndFallbackErrors(t *testing.T) {
 	}
 }
 
+func TestProcNetConnections(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "net"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	header := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	files := map[string]string{
+		"tcp": header +
+			"   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1\n" +
+			"   1: 0F02000A:0016 0102000A:D431 01 00000000:00000000 00:00000000 00000000     0        0 2 1\n" +
+			"   2: 0F02000A:0050 0102000A:D432 06 00000000:00000000 00:00000000 00000000     0        0 0 1\n" +
+			"   3: 0F02000A:0050 0302000A:D433 01 00000000:00000000 00:00000000 00000000     0        0 3 1\n",
+		"tcp6": header +
+			"   0: 00000000000000000000000000000000:0016 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 4 1\n" +
+			// 与 tcp 中 10.0.2.1 相同的 IPv4 映射地址
+			"   1: 0000000000000000FFFF00000F02000A:0050 0000000000000000FFFF00000102000A:D434 08 00000000:00000000 00:00000000 00000000     0        0 5 1\n" +
+			"   2: 000080FE00000000FF005450B6AD1DFE:0050 000080FE00000000FF005450B6AD1DFF:D435 01 00000000:00000000 00:00000000 00000000     0        0 6 1\n",
+		"udp": header +
+			"   0: 00000000:0044 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 7 2\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, "net", name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := procNetConnections(root)
+	if err != nil {
+		t.Fatalf("procNetConnections failed: %v", err)
+	}
+	if stats.TCP != 7 || stats.UDP != 1 {
+		t.Errorf("tcp=%d udp=%d, want 7 and 1", stats.TCP, stats.UDP)
+	}
+	if stats.Listen != 2 {
+		t.Errorf("listen = %d, want 2", stats.Listen)
+	}
+	// 10.0.2.1、10.0.2.3 与一个 IPv6 地址
+	if stats.RemotePeers != 3 {
+		t.Errorf("remote peers = %d, want 3", stats.RemotePeers)
+	}
+	want := map[string]map[string]int{
+		"ipv4": {"LISTEN": 1, "ESTABLISHED": 2, "TIME_WAIT": 1},
+		"ipv6": {"LISTEN": 1, "CLOSE_WAIT": 1, "ESTABLISHED": 1},
+	}
+	for family, states := range want {
+		for state, n := range states {
+			if got := stats.TCPStates[family][state]; got != n {
+				t.Errorf("%s %s = %d, want %d", family, state, got, n)
+			}
+		}
+	}
+}
+
 func TestParseNics(t *testing.T) {
 	tests := []struct {
 		name     string
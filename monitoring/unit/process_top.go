@@ -0,0 +1,134 @@
+package monitoring
+
+import (
+	"os/user"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ProcessInfo 单个进程的资源占用
+type ProcessInfo struct {
+	PID   int32   `json:"pid"`
+	Name  string  `json:"name"`
+	User  string  `json:"user"`
+	CPU   float64 `json:"cpu"`   // 两次采样之间的 CPU 使用率，按单核计算，多线程进程可能超过 100
+	RSS   uint64  `json:"rss"`   // 常驻内存，单位字节
+	Files int     `json:"files"` // 打开的文件描述符数量，无权限读取时为 -1
+}
+
+// ProcessTop 按 CPU 与内存排序的前 N 个进程
+type ProcessTop struct {
+	ByCPU    []ProcessInfo `json:"by_cpu"`
+	ByMemory []ProcessInfo `json:"by_memory"`
+}
+
+// procSample 平台相关代码采集的单个进程快照
+type procSample struct {
+	pid        int32
+	start      uint64 // 进程启动时间，与 pid 一起识别同一进程，避免 pid 复用导致误算
+	name       string
+	uid        string // 用户 ID，Windows 上直接为用户名
+	cpuSeconds float64
+	rss        uint64
+}
+
+type procKey struct {
+	pid   int32
+	start uint64
+}
+
+// procTopState 上一次的进程 CPU 时间采样
+var procTopState struct {
+	sync.Mutex
+	cpu map[procKey]float64
+	at  time.Time
+}
+
+// TopProcesses 返回 CPU 与内存占用最高的 n 个进程。首次调用时没有上一次采样，CPU 使用率为 0。
+func TopProcesses(n int) (ProcessTop, error) {
+	samples, err := listProcSamples()
+	if err != nil {
+		return ProcessTop{}, err
+	}
+	now := time.Now()
+
+	procTopState.Lock()
+	prev, elapsed := procTopState.cpu, now.Sub(procTopState.at)
+	cur := make(map[procKey]float64, len(samples))
+	for _, s := range samples {
+		cur[procKey{s.pid, s.start}] = s.cpuSeconds
+	}
+	procTopState.cpu, procTopState.at = cur, now
+	procTopState.Unlock()
+
+	byCPU, byMemory := selectTopProcesses(samples, prev, elapsed, n)
+	info := ProcessTop{ByCPU: make([]ProcessInfo, len(byCPU)), ByMemory: make([]ProcessInfo, len(byMemory))}
+	// 只为入选的进程查询用户名与文件描述符，避免遍历所有进程
+	files := make(map[int32]int)
+	fill := func(p ProcessInfo) ProcessInfo {
+		p.User = lookupProcessUser(p.User)
+		if n, ok := files[p.PID]; ok {
+			p.Files = n
+		} else {
+			p.Files = procOpenFiles(p.PID)
+			files[p.PID] = p.Files
+		}
+		return p
+	}
+	for i, p := range byCPU {
+		info.ByCPU[i] = fill(p)
+	}
+	for i, p := range byMemory {
+		info.ByMemory[i] = fill(p)
+	}
+	return info, nil
+}
+
+// selectTopProcesses 计算 CPU 使用率并分别按 CPU 与 RSS 选出前 n 个进程，User 字段暂存用户 ID
+func selectTopProcesses(samples []procSample, prev map[procKey]float64, elapsed time.Duration, n int) (byCPU, byMemory []ProcessInfo) {
+	procs := make([]ProcessInfo, len(samples))
+	seconds := elapsed.Seconds()
+	for i, s := range samples {
+		procs[i] = ProcessInfo{PID: s.pid, Name: s.name, User: s.uid, RSS: s.rss}
+		if last, ok := prev[procKey{s.pid, s.start}]; ok && seconds > 0 && s.cpuSeconds >= last {
+			procs[i].CPU = (s.cpuSeconds - last) / seconds * 100
+		}
+	}
+	top := func(less func(a, b ProcessInfo) bool) []ProcessInfo {
+		sorted := append([]ProcessInfo(nil), procs...)
+		sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+		if len(sorted) > n {
+			sorted = sorted[:n]
+		}
+		return sorted
+	}
+	byCPU = top(func(a, b ProcessInfo) bool {
+		if a.CPU != b.CPU {
+			return a.CPU > b.CPU
+		}
+		return a.RSS > b.RSS
+	})
+	byMemory = top(func(a, b ProcessInfo) bool { return a.RSS > b.RSS })
+	return byCPU, byMemory
+}
+
+// processUsers 用户 ID 到用户名的缓存
+var processUsers sync.Map
+
+// lookupProcessUser 将用户 ID 解析为用户名，无法解析（如容器中读取宿主机进程）时保留 ID
+func lookupProcessUser(uid string) string {
+	if _, err := strconv.Atoi(uid); err != nil {
+		return uid
+	}
+	if name, ok := processUsers.Load(uid); ok {
+		return name.(string)
+	}
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	processUsers.Store(uid, name)
+	return name
+}
@@ -0,0 +1,89 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// clockTicks /proc/<pid>/stat 中 CPU 时间的单位 (USER_HZ)，Linux 上固定为 100
+const clockTicks = 100
+
+// listProcSamples 遍历 /proc（或 HostProc）读取各进程的 CPU 时间与 RSS
+func listProcSamples() ([]procSample, error) {
+	return listProcSamplesFrom(procRoot())
+}
+
+func listProcSamplesFrom(root string) ([]procSample, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := uint64(os.Getpagesize())
+	var samples []procSample
+	for _, entry := range entries {
+		pid, err := strconv.ParseInt(entry.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		// 进程可能在遍历过程中退出
+		s, err := readProcStat(filepath.Join(dir, "stat"), pageSize)
+		if err != nil {
+			continue
+		}
+		s.pid = int32(pid)
+		if info, err := os.Stat(dir); err == nil {
+			if st, ok := info.Sys().(*syscall.Stat_t); ok {
+				s.uid = strconv.FormatUint(uint64(st.Uid), 10)
+			}
+		}
+		samples = append(samples, s)
+	}
+	return samples, nil
+}
+
+// readProcStat 解析 /proc/<pid>/stat，进程名位于括号中且可能包含空格与括号
+func readProcStat(path string, pageSize uint64) (procSample, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return procSample{}, err
+	}
+	line := string(data)
+	open, end := strings.IndexByte(line, '('), strings.LastIndexByte(line, ')')
+	if open < 0 || end < open {
+		return procSample{}, fmt.Errorf("malformed %s", path)
+	}
+	// 括号之后从第 3 个字段 state 开始
+	fields := strings.Fields(line[end+1:])
+	if len(fields) < 22 {
+		return procSample{}, fmt.Errorf("malformed %s", path)
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	start, _ := strconv.ParseUint(fields[19], 10, 64)
+	rss, _ := strconv.ParseInt(fields[21], 10, 64)
+	s := procSample{
+		name:       line[open+1 : end],
+		start:      start,
+		cpuSeconds: float64(utime+stime) / clockTicks,
+	}
+	if rss > 0 {
+		s.rss = uint64(rss) * pageSize
+	}
+	return s, nil
+}
+
+// procOpenFiles 统计 /proc/<pid>/fd 下的条目数，读取他人进程需要 root 权限
+func procOpenFiles(pid int32) int {
+	entries, err := os.ReadDir(filepath.Join(procRoot(), strconv.Itoa(int(pid)), "fd"))
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"os"
+	"testing"
+)
+
+func TestListProcSamplesFrom(t *testing.T) {
+	root := t.TempDir()
+	// 进程名中包含空格与括号
+	writeSysFile(t, root, "42/stat", "42 (my (weird) proc) S 1 42 42 0 -1 4194560 100 0 0 0 250 50 0 0 20 0 1 0 12345 1000000 300 18446744073709551615")
+	writeSysFile(t, root, "43/stat", "garbage")
+	writeSysFile(t, root, "self/stat", "1 (x) S")
+
+	samples, err := listProcSamplesFrom(root)
+	if err != nil {
+		t.Fatalf("listProcSamplesFrom failed: %v", err)
+	}
+	if len(samples) != 1 {
+		t.Fatalf("samples = %+v, want one", samples)
+	}
+	s := samples[0]
+	if s.pid != 42 || s.name != "my (weird) proc" || s.start != 12345 || s.cpuSeconds != 3 {
+		t.Errorf("sample = %+v", s)
+	}
+	if want := 300 * uint64(os.Getpagesize()); s.rss != want {
+		t.Errorf("rss = %d, want %d", s.rss, want)
+	}
+	if s.uid == "" {
+		t.Error("expected uid from directory owner")
+	}
+}
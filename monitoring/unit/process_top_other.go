@@ -0,0 +1,53 @@
+//go:build !linux
+
+package monitoring
+
+import (
+	"fmt"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// listProcSamples 非 Linux 平台通过 gopsutil 读取各进程的 CPU 时间与 RSS
+func listProcSamples() ([]procSample, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]procSample, 0, len(procs))
+	for _, p := range procs {
+		// 进程可能在遍历过程中退出，或因权限不足无法读取
+		times, err := p.Times()
+		if err != nil {
+			continue
+		}
+		s := procSample{pid: p.Pid, cpuSeconds: times.User + times.System}
+		if created, err := p.CreateTime(); err == nil {
+			s.start = uint64(created)
+		}
+		s.name, _ = p.Name()
+		if mem, err := p.MemoryInfo(); err == nil {
+			s.rss = mem.RSS
+		}
+		if uids, err := p.Uids(); err == nil && len(uids) > 0 {
+			s.uid = fmt.Sprint(uids[0])
+		} else if name, err := p.Username(); err == nil {
+			s.uid = name
+		}
+		samples = append(samples, s)
+	}
+	return samples, nil
+}
+
+// procOpenFiles Windows 上返回句柄数，其他平台返回文件描述符数
+func procOpenFiles(pid int32) int {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return -1
+	}
+	n, err := p.NumFDs()
+	if err != nil {
+		return -1
+	}
+	return int(n)
+}
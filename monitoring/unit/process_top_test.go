@@ -0,0 +1,36 @@
+package monitoring
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSelectTopProcesses(t *testing.T) {
+	samples := []procSample{
+		{pid: 1, start: 10, name: "init", uid: "0", cpuSeconds: 5, rss: 10 << 20},
+		{pid: 2, start: 20, name: "busy", uid: "1000", cpuSeconds: 12, rss: 1 << 20},
+		{pid: 3, start: 30, name: "db", uid: "999", cpuSeconds: 3, rss: 500 << 20},
+		// pid 复用后的新进程不应与旧进程计算差值
+		{pid: 4, start: 45, name: "reused", uid: "0", cpuSeconds: 50, rss: 2 << 20},
+	}
+	prev := map[procKey]float64{
+		{1, 10}: 4.5,
+		{2, 20}: 10,
+		{3, 30}: 3,
+		{4, 40}: 1,
+	}
+
+	byCPU, byMemory := selectTopProcesses(samples, prev, 2*time.Second, 2)
+	if len(byCPU) != 2 || byCPU[0].Name != "busy" || byCPU[1].Name != "init" {
+		t.Fatalf("byCPU = %+v", byCPU)
+	}
+	if byCPU[0].CPU != 100 || byCPU[1].CPU != 25 {
+		t.Errorf("cpu = %v/%v, want 100/25", byCPU[0].CPU, byCPU[1].CPU)
+	}
+	if len(byMemory) != 2 || byMemory[0].Name != "db" || byMemory[1].Name != "init" {
+		t.Errorf("byMemory = %+v", byMemory)
+	}
+	if byCPU[0].User != "1000" {
+		t.Errorf("user = %q, want uid before lookup", byCPU[0].User)
+	}
+}
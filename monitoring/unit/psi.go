@@ -0,0 +1,25 @@
+package monitoring
+
+// PressureStat PSI 中 some 或 full 一行的数据
+type PressureStat struct {
+	Avg10  float64 `json:"avg10"` // 最近 10 秒内任务因资源不足而停顿的时间占比 (0-100)
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+	Total  uint64  `json:"total"` // 累计停顿时间，单位微秒
+}
+
+// PressureResource 单个资源的压力数据
+type PressureResource struct {
+	Some PressureStat  `json:"some"`           // 至少一个任务停顿
+	Full *PressureStat `json:"full,omitempty"` // 所有非空闲任务同时停顿，旧内核的 cpu 没有此项
+}
+
+// PressureInfo /proc/pressure 下的压力停顿信息 (PSI)。
+// 不可用时 Available 为 false 并在 Reason 中说明原因，而不是上报全零数据。
+type PressureInfo struct {
+	Available bool              `json:"available"`
+	Reason    string            `json:"reason,omitempty"`
+	CPU       *PressureResource `json:"cpu,omitempty"`
+	Memory    *PressureResource `json:"memory,omitempty"`
+	IO        *PressureResource `json:"io,omitempty"`
+}
@@ -0,0 +1,117 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Pressure 读取 /proc/pressure（或 HostProc 下）的 cpu、memory 与 io 压力数据
+func Pressure() PressureInfo {
+	return readPressure(procRoot())
+}
+
+func readPressure(root string) PressureInfo {
+	var info PressureInfo
+	var firstErr error
+	for _, r := range []struct {
+		name string
+		dst  **PressureResource
+	}{
+		{"cpu", &info.CPU},
+		{"memory", &info.Memory},
+		{"io", &info.IO},
+	} {
+		res, err := readPressureFile(filepath.Join(root, "pressure", r.name))
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		*r.dst = res
+	}
+	if info.CPU != nil || info.Memory != nil || info.IO != nil {
+		info.Available = true
+		return info
+	}
+	info.Reason = pressureUnavailableReason(firstErr)
+	return info
+}
+
+// pressureUnavailableReason 将读取错误转换为易懂的原因说明
+func pressureUnavailableReason(err error) string {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return "kernel does not support PSI (requires Linux 4.20+ with CONFIG_PSI)"
+	case errors.Is(err, syscall.EOPNOTSUPP):
+		// 编译了 PSI 但默认关闭 (CONFIG_PSI_DEFAULT_DISABLED) 时读取返回 EOPNOTSUPP
+		return "PSI is disabled, boot with psi=1 to enable it"
+	case errors.Is(err, os.ErrPermission):
+		return "permission denied reading /proc/pressure"
+	case err != nil:
+		return err.Error()
+	}
+	return "pressure stall information unavailable"
+}
+
+// readPressureFile 解析形如 "some avg10=0.00 avg60=0.00 avg300=0.00 total=0" 的内容
+func readPressureFile(path string) (*PressureResource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	res := &PressureResource{}
+	foundSome := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var stat PressureStat
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			var err error
+			switch key {
+			case "avg10":
+				stat.Avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				stat.Avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				stat.Avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				stat.Total, err = strconv.ParseUint(value, 10, 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("malformed %s: %q", path, field)
+			}
+		}
+		switch fields[0] {
+		case "some":
+			res.Some = stat
+			foundSome = true
+		case "full":
+			res.Full = &stat
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !foundSome {
+		return nil, fmt.Errorf("malformed %s: missing some line", path)
+	}
+	return res, nil
+}
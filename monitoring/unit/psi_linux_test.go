@@ -0,0 +1,42 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadPressure(t *testing.T) {
+	root := t.TempDir()
+	writeSysFile(t, root, "pressure/cpu", "some avg10=5.31 avg60=5.72 avg300=3.96 total=88399733")
+	writeSysFile(t, root, "pressure/memory", "some avg10=0.00 avg60=0.10 avg300=0.05 total=1200\nfull avg10=0.00 avg60=0.02 avg300=0.01 total=300")
+
+	info := readPressure(root)
+	if !info.Available || info.Reason != "" {
+		t.Fatalf("expected PSI available, got %+v", info)
+	}
+	if info.CPU == nil || info.CPU.Some != (PressureStat{Avg10: 5.31, Avg60: 5.72, Avg300: 3.96, Total: 88399733}) || info.CPU.Full != nil {
+		t.Errorf("cpu = %+v", info.CPU)
+	}
+	if info.Memory == nil || info.Memory.Full == nil || info.Memory.Full.Total != 300 || info.Memory.Some.Avg60 != 0.10 {
+		t.Errorf("memory = %+v", info.Memory)
+	}
+	if info.IO != nil {
+		t.Errorf("io = %+v, want nil when missing", info.IO)
+	}
+}
+
+func TestReadPressureUnavailable(t *testing.T) {
+	info := readPressure(filepath.Join(t.TempDir(), "missing"))
+	if info.Available || info.CPU != nil || !strings.Contains(info.Reason, "does not support PSI") {
+		t.Errorf("unexpected pressure info: %+v", info)
+	}
+
+	root := t.TempDir()
+	writeSysFile(t, root, "pressure/cpu", "some avg10=abc")
+	if info := readPressure(root); info.Available || !strings.Contains(info.Reason, "malformed") {
+		t.Errorf("unexpected pressure info for malformed file: %+v", info)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package monitoring
+
+// Pressure PSI 仅在 Linux 上可用
+func Pressure() PressureInfo {
+	return PressureInfo{Reason: "pressure stall information is only available on Linux"}
+}
@@ -0,0 +1,28 @@
+package monitoring
+
+// SensorReading 一个带标签的传感器读数
+type SensorReading struct {
+	Label    string  `json:"label"`
+	Value    float64 `json:"value"`
+	Max      float64 `json:"max,omitempty"`      // 告警阈值，未知时省略
+	Critical float64 `json:"critical,omitempty"` // 临界阈值，未知时省略
+}
+
+// CPUFreqInfo CPU 频率，单位 MHz
+type CPUFreqInfo struct {
+	Current float64 `json:"current"` // 所有逻辑核心当前频率的平均值
+	Max     float64 `json:"max"`     // 所有逻辑核心中的最高频率
+}
+
+// SensorsInfo 硬件传感器数据，不支持的平台或无传感器时各字段为空
+type SensorsInfo struct {
+	Temperatures []SensorReading `json:"temperatures,omitempty"` // 温度，单位摄氏度
+	Fans         []SensorReading `json:"fans,omitempty"`         // 风扇转速，单位 RPM
+	Voltages     []SensorReading `json:"voltages,omitempty"`     // 电压，单位伏特
+	CPUFreq      *CPUFreqInfo    `json:"cpu_freq,omitempty"`
+}
+
+// Empty 没有任何传感器数据时返回 true
+func (s SensorsInfo) Empty() bool {
+	return len(s.Temperatures) == 0 && len(s.Fans) == 0 && len(s.Voltages) == 0 && s.CPUFreq == nil
+}
@@ -0,0 +1,178 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sysRoot 返回 sysfs 挂载点，容器中可通过 HOST_SYS 指向宿主机的 /sys
+func sysRoot() string {
+	if flags.HostSys != "" {
+		return flags.HostSys
+	}
+	return "/sys"
+}
+
+// Sensors 从 hwmon、thermal_zone 与 cpufreq 读取温度、风扇、电压与 CPU 频率
+func Sensors() SensorsInfo {
+	return readSensors(sysRoot())
+}
+
+func readSensors(root string) SensorsInfo {
+	var info SensorsInfo
+	chips := make(map[string]bool)
+
+	hwmons, _ := filepath.Glob(filepath.Join(root, "class", "hwmon", "hwmon*"))
+	sortByTrailingNumber(hwmons)
+	for _, dir := range hwmons {
+		// 旧内核的传感器文件位于 device 子目录中
+		if _, err := os.Stat(filepath.Join(dir, "name")); err != nil {
+			if _, err := os.Stat(filepath.Join(dir, "device", "name")); err == nil {
+				dir = filepath.Join(dir, "device")
+			}
+		}
+		chip := readSysString(filepath.Join(dir, "name"))
+		if chip == "" {
+			chip = filepath.Base(dir)
+		}
+		chips[chip] = true
+
+		// 温度单位为毫摄氏度，电压单位为毫伏
+		info.Temperatures = append(info.Temperatures, readHwmonInputs(dir, chip, "temp", 1000)...)
+		info.Fans = append(info.Fans, readHwmonInputs(dir, chip, "fan", 1)...)
+		info.Voltages = append(info.Voltages, readHwmonInputs(dir, chip, "in", 1000)...)
+	}
+
+	zones, _ := filepath.Glob(filepath.Join(root, "class", "thermal", "thermal_zone*"))
+	sortByTrailingNumber(zones)
+	for _, dir := range zones {
+		zoneType := readSysString(filepath.Join(dir, "type"))
+		// 同时注册为 hwmon 的温区（如 acpitz）已在上面读取过
+		if zoneType == "" || chips[zoneType] {
+			continue
+		}
+		temp, ok := readSysFloat(filepath.Join(dir, "temp"))
+		if !ok {
+			continue
+		}
+		info.Temperatures = append(info.Temperatures, SensorReading{
+			Label: "thermal/" + zoneType,
+			Value: temp / 1000,
+		})
+	}
+
+	info.CPUFreq = readCPUFreq(root)
+	return info
+}
+
+// readHwmonInputs 读取 hwmon 目录下某类传感器的 <kind>N_input，scale 为换算到标准单位的除数
+func readHwmonInputs(dir, chip, kind string, scale float64) []SensorReading {
+	inputs, _ := filepath.Glob(filepath.Join(dir, kind+"*_input"))
+	type indexed struct {
+		n int
+		SensorReading
+	}
+	var found []indexed
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), "_input")
+		// 避免 "in" 匹配到 intrusion 等其他前缀
+		n, err := strconv.Atoi(strings.TrimPrefix(name, kind))
+		if err != nil {
+			continue
+		}
+		value, ok := readSysFloat(input)
+		if !ok {
+			continue
+		}
+		label := readSysString(filepath.Join(dir, name+"_label"))
+		if label == "" {
+			label = name
+		}
+		reading := SensorReading{Label: chip + "/" + label, Value: value / scale}
+		if limit, ok := readSysFloat(filepath.Join(dir, name+"_max")); ok {
+			reading.Max = limit / scale
+		}
+		if crit, ok := readSysFloat(filepath.Join(dir, name+"_crit")); ok {
+			reading.Critical = crit / scale
+		}
+		found = append(found, indexed{n, reading})
+	}
+	// 按传感器编号排序，使 temp10 排在 temp2 之后
+	sort.Slice(found, func(i, j int) bool { return found[i].n < found[j].n })
+	readings := make([]SensorReading, len(found))
+	for i, f := range found {
+		readings[i] = f.SensorReading
+	}
+	return readings
+}
+
+// readCPUFreq 汇总各逻辑核心的 cpufreq 数据，单位由 kHz 换算为 MHz
+func readCPUFreq(root string) *CPUFreqInfo {
+	cpus, _ := filepath.Glob(filepath.Join(root, "devices", "system", "cpu", "cpu[0-9]*", "cpufreq"))
+	var sum, maxFreq float64
+	count := 0
+	for _, dir := range cpus {
+		cur, ok := readSysFloat(filepath.Join(dir, "scaling_cur_freq"))
+		if !ok {
+			cur, ok = readSysFloat(filepath.Join(dir, "cpuinfo_cur_freq"))
+		}
+		if !ok {
+			continue
+		}
+		sum += cur
+		count++
+		if m, ok := readSysFloat(filepath.Join(dir, "cpuinfo_max_freq")); ok && m > maxFreq {
+			maxFreq = m
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	return &CPUFreqInfo{Current: sum / float64(count) / 1000, Max: maxFreq / 1000}
+}
+
+func readSysString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readSysFloat 读取数值文件，传感器不可用时内核会返回 ENODATA 等错误
+func readSysFloat(path string) (float64, bool) {
+	s := readSysString(path)
+	if s == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// trailingNumber 返回字符串末尾的数字，没有时返回 -1
+func trailingNumber(s string) int {
+	i := len(s)
+	for i > 0 && s[i-1] >= '0' && s[i-1] <= '9' {
+		i--
+	}
+	n, err := strconv.Atoi(s[i:])
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// sortByTrailingNumber 按末尾数字排序，使 hwmon10 排在 hwmon2 之后
+func sortByTrailingNumber(paths []string) {
+	sort.SliceStable(paths, func(i, j int) bool {
+		return trailingNumber(paths[i]) < trailingNumber(paths[j])
+	})
+}
@@ -0,0 +1,81 @@
+//go:build linux
+
+package monitoring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSysFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadSensors(t *testing.T) {
+	root := t.TempDir()
+	writeSysFile(t, root, "class/hwmon/hwmon0/name", "coretemp")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp1_input", "52000")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp1_label", "Package id 0")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp1_max", "80000")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp1_crit", "100000")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp10_input", "48000")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp2_input", "47000")
+	// 不可用的传感器读取失败时应跳过
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp3_input", "")
+	writeSysFile(t, root, "class/hwmon/hwmon1/device/name", "nct6775")
+	writeSysFile(t, root, "class/hwmon/hwmon1/device/fan1_input", "1250")
+	writeSysFile(t, root, "class/hwmon/hwmon1/device/in0_input", "1224")
+	writeSysFile(t, root, "class/hwmon/hwmon1/device/in0_label", "Vcore")
+	writeSysFile(t, root, "class/hwmon/hwmon1/device/intrusion0_alarm", "0")
+	writeSysFile(t, root, "class/hwmon/hwmon2/name", "acpitz")
+	writeSysFile(t, root, "class/hwmon/hwmon2/temp1_input", "27800")
+	writeSysFile(t, root, "class/thermal/thermal_zone0/type", "acpitz")
+	writeSysFile(t, root, "class/thermal/thermal_zone0/temp", "27800")
+	writeSysFile(t, root, "class/thermal/thermal_zone1/type", "x86_pkg_temp")
+	writeSysFile(t, root, "class/thermal/thermal_zone1/temp", "53000")
+	writeSysFile(t, root, "devices/system/cpu/cpu0/cpufreq/scaling_cur_freq", "1200000")
+	writeSysFile(t, root, "devices/system/cpu/cpu0/cpufreq/cpuinfo_max_freq", "3600000")
+	writeSysFile(t, root, "devices/system/cpu/cpu1/cpufreq/scaling_cur_freq", "2800000")
+	writeSysFile(t, root, "devices/system/cpu/cpu1/cpufreq/cpuinfo_max_freq", "4000000")
+
+	info := readSensors(root)
+
+	wantTemps := []SensorReading{
+		{Label: "coretemp/Package id 0", Value: 52, Max: 80, Critical: 100},
+		{Label: "coretemp/temp2", Value: 47},
+		{Label: "coretemp/temp10", Value: 48},
+		{Label: "acpitz/temp1", Value: 27.8},
+		{Label: "thermal/x86_pkg_temp", Value: 53},
+	}
+	if len(info.Temperatures) != len(wantTemps) {
+		t.Fatalf("temperatures = %+v, want %+v", info.Temperatures, wantTemps)
+	}
+	for i, want := range wantTemps {
+		if info.Temperatures[i] != want {
+			t.Errorf("temperature %d = %+v, want %+v", i, info.Temperatures[i], want)
+		}
+	}
+	if len(info.Fans) != 1 || info.Fans[0] != (SensorReading{Label: "nct6775/fan1", Value: 1250}) {
+		t.Errorf("fans = %+v", info.Fans)
+	}
+	if len(info.Voltages) != 1 || info.Voltages[0] != (SensorReading{Label: "nct6775/Vcore", Value: 1.224}) {
+		t.Errorf("voltages = %+v", info.Voltages)
+	}
+	if info.CPUFreq == nil || *info.CPUFreq != (CPUFreqInfo{Current: 2000, Max: 4000}) {
+		t.Errorf("cpu freq = %+v, want current 2000 max 4000", info.CPUFreq)
+	}
+}
+
+func TestReadSensorsMissingRoot(t *testing.T) {
+	if info := readSensors(filepath.Join(t.TempDir(), "missing")); !info.Empty() {
+		t.Errorf("expected no sensors, got %+v", info)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package monitoring
+
+// Sensors 仅在 Linux 上通过 sysfs 采集传感器数据
+func Sensors() SensorsInfo {
+	return SensorsInfo{}
+}
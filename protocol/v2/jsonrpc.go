@@ -8,17 +8,22 @@ import (
 )
 
 const (
-	Version               = "2.0"
-	MethodAgentReport     = "agent.report"
-	MethodAgentBasicInfo  = "agent.basicInfo"
-	MethodAgentPingResult = "agent.pingResult"
-	MethodAgentTaskResult = "agent.taskResult"
-	MethodAgentExec       = "agent.exec"
-	MethodAgentPing       = "agent.ping"
-	MethodAgentMessage    = "agent.message"
-	MethodAgentEvent      = "agent.event"
-	MethodAgentTerminal   = "agent.terminal.request"
-	MethodAgentPull       = "agent.pull"
+	Version                     = "2.0"
+	MethodAgentReport           = "agent.report"
+	MethodAgentBasicInfo        = "agent.basicInfo"
+	MethodAgentPingResult       = "agent.pingResult"
+	MethodAgentPingResults      = "agent.pingResults"
+	MethodAgentTaskResult       = "agent.taskResult"
+	MethodAgentExec             = "agent.exec"
+	MethodAgentPing             = "agent.ping"
+	MethodAgentCert             = "agent.cert"
+	MethodAgentBandwidth        = "agent.bandwidth"
+	MethodAgentMessage          = "agent.message"
+	MethodAgentEvent            = "agent.event"
+	MethodAgentTerminal         = "agent.terminal.request"
+	MethodAgentTerminalSessions = "agent.terminal.sessions"
+	MethodAgentTunnelOpen       = "agent.tunnel.open"
+	MethodAgentPull             = "agent.pull"
 )
 
 type Request struct {
@@ -50,8 +55,9 @@ type Event struct {
 }
 
 type EventResult struct {
-	Status string  `json:"status,omitempty"`
-	Events []Event `json:"events,omitempty"`
+	Status       string   `json:"status,omitempty"`
+	Events       []Event  `json:"events,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
 }
 
 func NewNotification(method string, params interface{}) []byte {
@@ -81,16 +87,31 @@ type reportParams struct {
 	AckEventIDs []string        `json:"ack_event_ids,omitempty"`
 }
 
-func BuildPingResultPayload(taskID uint, pingType string, value int, finishedAt time.Time) interface{} {
+// PingResult 探测结果，Detail 携带特定探测类型（如 cert）的附加信息
+type PingResult struct {
+	TaskID     uint        `json:"task_id"`
+	PingType   string      `json:"ping_type"`
+	PingMethod string      `json:"ping_method,omitempty"`
+	Address    string      `json:"address,omitempty"`
+	Value      int         `json:"value"`
+	FinishedAt time.Time   `json:"finished_at"`
+	Detail     interface{} `json:"detail,omitempty"`
+}
+
+func BuildPingResultPayload(result PingResult) interface{} {
 	return Request{
 		JSONRPC: Version,
 		Method:  MethodAgentPingResult,
-		Params: map[string]interface{}{
-			"task_id":     taskID,
-			"ping_type":   pingType,
-			"value":       value,
-			"finished_at": finishedAt.Format(time.RFC3339Nano),
-		},
+		Params:  result,
+	}
+}
+
+// BuildPingResultsPayload 将多个探测结果合并为一次 agent.pingResults 调用
+func BuildPingResultsPayload(results []PingResult) interface{} {
+	return Request{
+		JSONRPC: Version,
+		Method:  MethodAgentPingResults,
+		Params:  map[string]interface{}{"results": results},
 	}
 }
 
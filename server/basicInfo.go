@@ -2,13 +2,18 @@ package server
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/komari-monitor/komari-agent/dnsresolver"
 	monitoring "github.com/komari-monitor/komari-agent/monitoring/unit"
 	"github.com/komari-monitor/komari-agent/protocol/transport"
@@ -20,31 +25,123 @@ import (
 
 var flags = pkg_flags.GlobalConfig
 
+const (
+	// basicInfoForceRefresh 内容未变化时强制重新上报的间隔
+	basicInfoForceRefresh = 24 * time.Hour
+	// ipRefreshInterval 公网 IP 查询结果的缓存时间，查询需要访问多个外部接口
+	ipRefreshInterval = time.Hour
+)
+
+// DoUploadBasicInfoWorks 按 InfoReportInterval 采集基础信息，仅在内容变化或到达强制刷新间隔时上报
 func DoUploadBasicInfoWorks() {
 	ticker := time.NewTicker(time.Duration(flags.InfoReportInterval) * time.Minute)
 	for range ticker.C {
-		err := uploadBasicInfo()
+		err := uploadBasicInfo(false)
 		if err != nil {
 			log.Println("Error uploading basic info:", err)
 		}
 	}
 }
+
+// UpdateBasicInfo 立即上报一次完整的基础信息
 func UpdateBasicInfo() {
-	err := uploadBasicInfo()
+	err := uploadBasicInfo(true)
 	if err != nil {
 		log.Println("Error uploading basic info:", err)
 	} else {
 		log.Println("Basic info uploaded successfully")
 	}
 }
-func uploadBasicInfo() error {
+
+// basicInfoTracker 记录上次成功上报的基础信息及其哈希，用于判断是否需要重新上报
+type basicInfoTracker struct {
+	mu     sync.Mutex
+	last   map[string]interface{}
+	hash   string
+	sentAt time.Time
+}
+
+var basicInfoState basicInfoTracker
+
+// due 计算内容哈希与变化的字段，返回是否需要上报
+func (t *basicInfoTracker) due(data map[string]interface{}, now time.Time) (hash string, changed []string, due bool) {
+	hash = hashBasicInfo(data)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.last == nil {
+		return hash, nil, true
+	}
+	changed = diffBasicInfo(t.last, data)
+	return hash, changed, hash != t.hash || now.Sub(t.sentAt) >= basicInfoForceRefresh
+}
+
+// reset 清除上次上报的哈希，使下一次检查必定上报；保留上次内容以便报告断开期间变化的字段
+func (t *basicInfoTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hash = ""
+}
+
+func (t *basicInfoTracker) markSent(data map[string]interface{}, hash string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.last = data
+	t.hash = hash
+	t.sentAt = now
+}
+
+func hashBasicInfo(data map[string]interface{}) string {
+	// map 按键排序序列化，结果稳定
+	b, _ := json.Marshal(data)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// diffBasicInfo 返回取值不同的字段名，按字母排序
+func diffBasicInfo(prev, cur map[string]interface{}) []string {
+	var changed []string
+	for key, value := range cur {
+		before, ok := prev[key]
+		a, _ := json.Marshal(before)
+		b, _ := json.Marshal(value)
+		if !ok || !bytes.Equal(a, b) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range prev {
+		if _, ok := cur[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+var ipCache struct {
+	sync.Mutex
+	ipv4, ipv6 string
+	fetchedAt  time.Time
+}
+
+// cachedIPAddress 返回缓存的 IP 地址，缓存过期或 refresh 为 true 时重新查询
+func cachedIPAddress(refresh bool) (ipv4, ipv6 string) {
+	ipCache.Lock()
+	defer ipCache.Unlock()
+	if refresh || ipCache.fetchedAt.IsZero() || time.Since(ipCache.fetchedAt) >= ipRefreshInterval {
+		ipCache.ipv4, ipCache.ipv6, _ = monitoring.GetIPAddress()
+		ipCache.fetchedAt = time.Now()
+	}
+	return ipCache.ipv4, ipCache.ipv6
+}
+
+func collectBasicInfo(refreshIP bool) map[string]interface{} {
 	cpu := monitoring.CpuStaticInfo()
 
 	osname := monitoring.OSName()
 	kernelVersion := monitoring.KernelVersion()
-	ipv4, ipv6, _ := monitoring.GetIPAddress()
+	ipv4, ipv6 := cachedIPAddress(refreshIP)
 
-	data := map[string]interface{}{
+	return map[string]interface{}{
 		"cpu_name":           cpu.CPUName,
 		"cpu_cores":          cpu.CPUCores,
 		"cpu_physical_cores": cpu.CPUPhysicalCores,
@@ -53,29 +150,87 @@ func uploadBasicInfo() error {
 		"kernel_version":     kernelVersion,
 		"ipv4":               ipv4,
 		"ipv6":               ipv6,
-		"mem_total":          monitoring.Ram().Total,
+		"mem_total":          monitoring.Ram(monitoring.ReadCgroup()).Total,
 		"swap_total":         monitoring.Swap().Total,
 		"disk_total":         monitoring.Disk().Total,
 		"gpu_name":           monitoring.GpuName(),
 		"virtualization":     monitoring.Virtualized(),
 		"version":            update.CurrentVersion,
 	}
+}
+
+// uploadBasicInfo 采集并上报基础信息。force 为 true 时忽略变化检测；
+// 到达强制刷新间隔时同时重新查询 IP 地址
+func uploadBasicInfo(force bool) error {
+	now := time.Now()
+	basicInfoState.mu.Lock()
+	refreshIP := now.Sub(basicInfoState.sentAt) >= basicInfoForceRefresh
+	basicInfoState.mu.Unlock()
+
+	data := collectBasicInfo(refreshIP)
+	hash, changed, due := basicInfoState.due(data, now)
+	if !due && !force {
+		return nil
+	}
+	if len(changed) > 0 {
+		log.Printf("Basic info changed: %s", strings.Join(changed, ", "))
+	}
+
+	// 优先通过当前的 v2 WebSocket 发送并等待确认，失败时回退到 HTTP 上报
+	if conn := currentV2Conn(); conn != nil && serverSupports(v2.MethodAgentBasicInfo) {
+		_, err := callV2(conn, v2.MethodAgentBasicInfo, map[string]interface{}{"info": data}, v2CallTimeout)
+		if err == nil {
+			basicInfoState.markSent(data, hash, now)
+			if len(changed) > 0 {
+				conn.WriteMessage(websocket.TextMessage, basicInfoChangedEvent(changed))
+			}
+			return nil
+		}
+		log.Printf("Failed to send basic info over WebSocket, falling back to HTTP: %v", err)
+	}
 
 	// 尝试上传完整数据
 	err := tryUploadData(data)
 	if err != nil {
+		legacy := make(map[string]interface{}, len(data))
+		for k, v := range data {
+			legacy[k] = v
+		}
 		// 兼容 <= 1.0.2
-		delete(data, "kernel_version")
+		delete(legacy, "kernel_version")
 		// 兼容 <= 1.2.0
-		delete(data, "cpu_physical_cores")
-		err = tryUploadData(data)
+		delete(legacy, "cpu_physical_cores")
+		err = tryUploadData(legacy)
 		if err != nil {
 			return err
 		}
 	}
+	basicInfoState.markSent(data, hash, now)
+	// v1 协议没有事件通知，仅在 v2 下通过 HTTP 发送变化事件
+	if len(changed) > 0 && uploadProtocolVersion() >= 2 {
+		if _, err := postV2Request(basicInfoChangedEvent(changed)); err != nil {
+			log.Printf("Failed to send basic info change event: %v", err)
+		}
+	}
 	return nil
 }
 
+// basicInfoChangedEvent 构造基础信息变化的事件通知
+func basicInfoChangedEvent(changed []string) []byte {
+	return v2.NewNotification(v2.MethodAgentEvent, map[string]interface{}{
+		"event":   "basic_info_changed",
+		"changed": changed,
+	})
+}
+
+// resendBasicInfo 在建立新连接后上报基础信息。面板可能在断开期间丢失了状态，因此不论内容是否变化都重新发送
+func resendBasicInfo() {
+	basicInfoState.reset()
+	if err := uploadBasicInfo(false); err != nil {
+		log.Println("Error uploading basic info:", err)
+	}
+}
+
 func tryUploadData(data map[string]interface{}) error {
 	protocolVersion := uploadProtocolVersion()
 	if protocolVersion >= 2 {
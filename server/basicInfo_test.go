@@ -0,0 +1,65 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffBasicInfo(t *testing.T) {
+	old := map[string]interface{}{"os": "Debian 12", "mem_total": uint64(1024), "ipv4": "1.2.3.4", "gpu_name": ""}
+	new := map[string]interface{}{"os": "Debian 12", "mem_total": uint64(2048), "ipv4": "1.2.3.4", "version": "1.0"}
+
+	got := diffBasicInfo(old, new)
+	want := []string{"gpu_name", "mem_total", "version"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diffBasicInfo = %v, want %v", got, want)
+	}
+	if changed := diffBasicInfo(old, old); len(changed) != 0 {
+		t.Fatalf("expected no changes, got %v", changed)
+	}
+}
+
+func TestBasicInfoTrackerOnlyResendsOnChange(t *testing.T) {
+	var tracker basicInfoTracker
+	now := time.Now()
+	data := map[string]interface{}{"os": "Debian 12", "cpu_cores": 4}
+
+	hash, changed, due := tracker.due(data, now)
+	if !due || changed != nil {
+		t.Fatalf("first upload should be due without changes, got due=%v changed=%v", due, changed)
+	}
+	tracker.markSent(data, hash, now)
+
+	same := map[string]interface{}{"cpu_cores": 4, "os": "Debian 12"}
+	if _, _, due := tracker.due(same, now.Add(time.Hour)); due {
+		t.Fatal("unchanged info should not be resent")
+	}
+	if _, _, due := tracker.due(same, now.Add(basicInfoForceRefresh)); !due {
+		t.Fatal("unchanged info should be resent after the forced refresh interval")
+	}
+
+	updated := map[string]interface{}{"os": "Debian 13", "cpu_cores": 4}
+	_, changed, due = tracker.due(updated, now.Add(time.Minute))
+	if !due || !reflect.DeepEqual(changed, []string{"os"}) {
+		t.Fatalf("expected os change to be due, got due=%v changed=%v", due, changed)
+	}
+}
+
+func TestBasicInfoTrackerResetForcesResend(t *testing.T) {
+	var tracker basicInfoTracker
+	now := time.Now()
+	data := map[string]interface{}{"os": "Debian 12"}
+	hash, _, _ := tracker.due(data, now)
+	tracker.markSent(data, hash, now)
+
+	tracker.reset()
+	_, changed, due := tracker.due(data, now.Add(time.Minute))
+	if !due || len(changed) != 0 {
+		t.Fatalf("expected unchanged info to be due after reset, got due=%v changed=%v", due, changed)
+	}
+	_, changed, _ = tracker.due(map[string]interface{}{"os": "Debian 13"}, now.Add(time.Minute))
+	if !reflect.DeepEqual(changed, []string{"os"}) {
+		t.Fatalf("expected changes to still be reported after reset, got %v", changed)
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	v2 "github.com/komari-monitor/komari-agent/protocol/v2"
+	"github.com/komari-monitor/komari-agent/ws"
+)
+
+const (
+	pingBatchWindow  = 500 * time.Millisecond // 结果合并窗口
+	pingBatchMaxSize = 100                    // 单批最多携带的结果数
+)
+
+// serverCapabilitiesHeader 服务端在 WebSocket 握手响应中声明支持的方法，逗号分隔
+const serverCapabilitiesHeader = "X-Komari-Capabilities"
+
+var serverCapabilities struct {
+	sync.RWMutex
+	methods map[string]struct{}
+}
+
+func setServerCapabilities(methods []string) {
+	set := make(map[string]struct{}, len(methods))
+	for _, m := range methods {
+		if m = strings.TrimSpace(m); m != "" {
+			set[m] = struct{}{}
+		}
+	}
+	serverCapabilities.Lock()
+	defer serverCapabilities.Unlock()
+	serverCapabilities.methods = set
+}
+
+func resetServerCapabilities() {
+	serverCapabilities.Lock()
+	defer serverCapabilities.Unlock()
+	serverCapabilities.methods = nil
+}
+
+func serverSupports(method string) bool {
+	serverCapabilities.RLock()
+	defer serverCapabilities.RUnlock()
+	_, ok := serverCapabilities.methods[method]
+	return ok
+}
+
+// pingBatcher 在短时间窗口内合并同一连接上的 ping 结果，以一次 agent.pingResults 调用发送
+type pingBatcher struct {
+	mu      sync.Mutex
+	pending map[*ws.SafeConn][]v2.PingResult
+	timer   *time.Timer
+	send    func(conn *ws.SafeConn, results []v2.PingResult)
+}
+
+var defaultPingBatcher = &pingBatcher{send: sendPingResultBatch}
+
+func (b *pingBatcher) add(conn *ws.SafeConn, result v2.PingResult) {
+	b.mu.Lock()
+	if b.pending == nil {
+		b.pending = make(map[*ws.SafeConn][]v2.PingResult)
+	}
+	b.pending[conn] = append(b.pending[conn], result)
+	var full []v2.PingResult
+	if len(b.pending[conn]) >= pingBatchMaxSize {
+		full = b.pending[conn]
+		delete(b.pending, conn)
+	} else if b.timer == nil {
+		b.timer = time.AfterFunc(pingBatchWindow, b.flush)
+	}
+	b.mu.Unlock()
+
+	if full != nil {
+		b.send(conn, full)
+	}
+}
+
+func (b *pingBatcher) flush() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.timer = nil
+	b.mu.Unlock()
+
+	for conn, results := range pending {
+		b.send(conn, results)
+	}
+}
+
+func sendPingResultBatch(conn *ws.SafeConn, results []v2.PingResult) {
+	payload := v2.BuildPingResultsPayload(results)
+	if conn == nil {
+		if err := postV2RPC(payload); err != nil {
+			log.Printf("Failed to upload %d ping results over POST: %v", len(results), err)
+		}
+		return
+	}
+	if err := conn.WriteJSON(payload); err != nil {
+		log.Printf("Failed to write %d ping results to WebSocket: %v", len(results), err)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	v2 "github.com/komari-monitor/komari-agent/protocol/v2"
+	"github.com/komari-monitor/komari-agent/ws"
+)
+
+func newRecordingPingBatcher() (*pingBatcher, func() [][]v2.PingResult) {
+	var mu sync.Mutex
+	var batches [][]v2.PingResult
+	b := &pingBatcher{send: func(conn *ws.SafeConn, results []v2.PingResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, results)
+	}}
+	return b, func() [][]v2.PingResult {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([][]v2.PingResult{}, batches...)
+	}
+}
+
+func TestPingBatcherCoalescesWithinWindow(t *testing.T) {
+	b, batches := newRecordingPingBatcher()
+	for i := 1; i <= 3; i++ {
+		b.add(nil, v2.PingResult{TaskID: uint(i)})
+	}
+	if got := len(batches()); got != 0 {
+		t.Fatalf("expected no batch before window elapses, got %d", got)
+	}
+	time.Sleep(pingBatchWindow + 200*time.Millisecond)
+	got := batches()
+	if len(got) != 1 || len(got[0]) != 3 {
+		t.Fatalf("expected one batch of 3 results, got %v", got)
+	}
+}
+
+func TestPingBatcherFlushesAtCap(t *testing.T) {
+	b, batches := newRecordingPingBatcher()
+	for i := 0; i < pingBatchMaxSize; i++ {
+		b.add(nil, v2.PingResult{TaskID: uint(i + 1)})
+	}
+	got := batches()
+	if len(got) != 1 || len(got[0]) != pingBatchMaxSize {
+		t.Fatalf("expected immediate batch of %d results, got %d batches", pingBatchMaxSize, len(got))
+	}
+}
+
+func TestServerCapabilitiesResetWithConnection(t *testing.T) {
+	preserveProtocolFallbackState(t)
+
+	setServerCapabilities([]string{" agent.pingResults ", ""})
+	if !serverSupports(v2.MethodAgentPingResults) {
+		t.Fatal("expected advertised capability to be recognised")
+	}
+	resetConnectionProtocolVersion()
+	if serverSupports(v2.MethodAgentPingResults) {
+		t.Fatal("expected capabilities to be cleared when the connection resets")
+	}
+}
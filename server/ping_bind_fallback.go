@@ -0,0 +1,36 @@
+//go:build !linux
+
+package server
+
+import (
+	"fmt"
+	"net"
+)
+
+// bindToInterface 在不支持 SO_BINDTODEVICE 的平台上，改为绑定该网卡上与目标同地址族的地址
+func bindToInterface(d *net.Dialer, iface string, targetIP string) error {
+	if d.LocalAddr != nil {
+		// 已指定 source_ip，以其为准
+		return nil
+	}
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return err
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return err
+	}
+	wantV4 := net.ParseIP(targetIP).To4() != nil
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if (ipNet.IP.To4() != nil) == wantV4 {
+			d.LocalAddr = &net.TCPAddr{IP: ipNet.IP}
+			return nil
+		}
+	}
+	return fmt.Errorf("interface %s has no usable address for %s", iface, targetIP)
+}
@@ -0,0 +1,27 @@
+//go:build linux
+
+package server
+
+import (
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindToInterface 通过 SO_BINDTODEVICE 将探测连接绑定到指定网卡
+func bindToInterface(d *net.Dialer, iface string, targetIP string) error {
+	if _, err := net.InterfaceByName(iface); err != nil {
+		return err
+	}
+	d.Control = func(network, address string, c syscall.RawConn) error {
+		var bindErr error
+		if err := c.Control(func(fd uintptr) {
+			bindErr = unix.BindToDevice(int(fd), iface)
+		}); err != nil {
+			return err
+		}
+		return bindErr
+	}
+	return nil
+}
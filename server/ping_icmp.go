@@ -0,0 +1,179 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	ping "github.com/prometheus-community/pro-bing"
+	"golang.org/x/net/icmp"
+)
+
+// ICMP 探测实际使用的方式
+const (
+	pingMethodICMP             = "icmp"              // 原始套接字 ICMP（需要 root 或 CAP_NET_RAW）
+	pingMethodICMPUnprivileged = "icmp_unprivileged" // 数据报 ICMP（Linux 依赖 net.ipv4.ping_group_range）
+	pingMethodTCP              = "tcp"               // TCP 握手近似
+)
+
+var (
+	icmpModeOnce sync.Once
+	icmpModeMu   sync.RWMutex
+	icmpMode     string
+)
+
+// DetectICMPMode 在启动时检测当前进程可用的 ICMP 方式，结果会被缓存
+func DetectICMPMode() string {
+	icmpModeOnce.Do(func() {
+		mode := probeICMPMode()
+		icmpModeMu.Lock()
+		icmpMode = mode
+		icmpModeMu.Unlock()
+		switch mode {
+		case pingMethodICMP:
+			log.Println("ICMP ping: using raw sockets")
+		case pingMethodICMPUnprivileged:
+			log.Println("ICMP ping: raw sockets unavailable, using unprivileged datagram ICMP")
+		default:
+			log.Println("ICMP ping: ICMP sockets unavailable, falling back to TCP handshake approximation")
+		}
+	})
+	icmpModeMu.RLock()
+	defer icmpModeMu.RUnlock()
+	return icmpMode
+}
+
+// downgradeICMPMode 在运行期间遇到权限错误时降级到下一种方式
+func downgradeICMPMode(from string) {
+	icmpModeMu.Lock()
+	defer icmpModeMu.Unlock()
+	if icmpMode != from {
+		return
+	}
+	switch from {
+	case pingMethodICMP:
+		icmpMode = pingMethodICMPUnprivileged
+	default:
+		icmpMode = pingMethodTCP
+	}
+	log.Printf("ICMP ping: %s not permitted, switching to %s", from, icmpMode)
+}
+
+func probeICMPMode() string {
+	// Windows 下 pro-bing 只支持特权模式，且普通用户即可使用
+	if runtime.GOOS == "windows" {
+		return pingMethodICMP
+	}
+	if canListenICMP("ip4:icmp", "ip6:ipv6-icmp") {
+		return pingMethodICMP
+	}
+	if canListenICMP("udp4", "udp6") {
+		return pingMethodICMPUnprivileged
+	}
+	return pingMethodTCP
+}
+
+func canListenICMP(v4Network, v6Network string) bool {
+	if conn, err := icmp.ListenPacket(v4Network, "0.0.0.0"); err == nil {
+		_ = conn.Close()
+		return true
+	}
+	if conn, err := icmp.ListenPacket(v6Network, "::"); err == nil {
+		_ = conn.Close()
+		return true
+	}
+	return false
+}
+
+func icmpPing(target string, timeout time.Duration) (int64, error) {
+	result, err := icmpProbe(target, timeout, pingOptions{})
+	return result.Latency, err
+}
+
+// icmpProbe 按检测到的方式执行 ICMP 探测，结果中带有实际使用的方式
+func icmpProbe(target string, timeout time.Duration, opts pingOptions) (probeResult, error) {
+	result := probeResult{Latency: -1, Method: pingMethodICMP}
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		host = target
+	}
+	// For ICMP, we only need the host/IP, port is irrelevant.
+	// If the host is an IPv6 literal, it might be wrapped in brackets.
+	host = strings.Trim(host, "[]")
+
+	// 先解析 IP 地址
+	ip, err := resolveIPWithOptions(host, opts)
+	if err != nil {
+		return result, err
+	}
+	result.Address = ip
+
+	for {
+		result.Method = DetectICMPMode()
+		if result.Method == pingMethodTCP {
+			result.Latency, err = tcpApproxPing(ip, timeout, opts)
+			return result, err
+		}
+		result.Latency, err = runPinger(ip, timeout, result.Method == pingMethodICMP, opts)
+		if err != nil && isPermissionError(err) {
+			downgradeICMPMode(result.Method)
+			continue
+		}
+		return result, err
+	}
+}
+
+func runPinger(ip string, timeout time.Duration, privileged bool, opts pingOptions) (int64, error) {
+	pinger, err := ping.NewPinger(ip)
+	if err != nil {
+		return -1, err
+	}
+	pinger.Count = 1
+	pinger.Timeout = timeout
+	pinger.Source = opts.SourceIP
+	pinger.InterfaceName = opts.Interface
+	pinger.SetPrivileged(privileged)
+	err = pinger.Run()
+	if err != nil {
+		return -1, err
+	}
+	stats := pinger.Statistics()
+	if stats.PacketsRecv == 0 {
+		return -1, errors.New("no packets received")
+	}
+	return stats.AvgRtt.Milliseconds(), nil
+}
+
+// tcpApproxPing 在无法使用 ICMP 时用 TCP 握手近似往返时延，
+// 对端返回 RST（连接被拒绝）同样说明主机可达，也计入结果
+func tcpApproxPing(ip string, timeout time.Duration, opts pingOptions) (int64, error) {
+	dialer, err := newProbeDialer(timeout, opts, ip)
+	if err != nil {
+		return -1, err
+	}
+	var lastErr error
+	for _, port := range []string{"80", "443"} {
+		start := time.Now()
+		conn, err := dialer.Dial("tcp", net.JoinHostPort(ip, port))
+		latency := time.Since(start).Milliseconds()
+		if err == nil {
+			conn.Close()
+			return latency, nil
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return latency, nil
+		}
+		lastErr = err
+	}
+	return -1, lastErr
+}
+
+func isPermissionError(err error) bool {
+	return errors.Is(err, os.ErrPermission) || errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES)
+}
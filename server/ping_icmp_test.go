@@ -0,0 +1,52 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectICMPModeReturnsKnownMethod(t *testing.T) {
+	switch mode := DetectICMPMode(); mode {
+	case pingMethodICMP, pingMethodICMPUnprivileged, pingMethodTCP:
+	default:
+		t.Fatalf("unexpected ICMP mode %q", mode)
+	}
+}
+
+func TestDowngradeICMPModeStepsTowardsTCP(t *testing.T) {
+	DetectICMPMode()
+	icmpModeMu.Lock()
+	original := icmpMode
+	icmpMode = pingMethodICMP
+	icmpModeMu.Unlock()
+	t.Cleanup(func() {
+		icmpModeMu.Lock()
+		icmpMode = original
+		icmpModeMu.Unlock()
+	})
+
+	downgradeICMPMode(pingMethodICMP)
+	if got := DetectICMPMode(); got != pingMethodICMPUnprivileged {
+		t.Fatalf("expected %q after first downgrade, got %q", pingMethodICMPUnprivileged, got)
+	}
+	// 过期的降级请求不应影响当前模式
+	downgradeICMPMode(pingMethodICMP)
+	if got := DetectICMPMode(); got != pingMethodICMPUnprivileged {
+		t.Fatalf("stale downgrade changed mode to %q", got)
+	}
+	downgradeICMPMode(pingMethodICMPUnprivileged)
+	if got := DetectICMPMode(); got != pingMethodTCP {
+		t.Fatalf("expected %q after second downgrade, got %q", pingMethodTCP, got)
+	}
+}
+
+func TestTCPApproxPingCountsRefusedAsReachable(t *testing.T) {
+	// 本机回环上无论 80/443 是否监听都会立即应答（握手成功或 RST），均视为可达
+	latency, err := tcpApproxPing("127.0.0.1", time.Second, pingOptions{})
+	if err != nil {
+		t.Fatalf("expected loopback to be reachable, got %v", err)
+	}
+	if latency < 0 {
+		t.Fatalf("invalid latency %d", latency)
+	}
+}
@@ -147,6 +147,7 @@ func resetConnectionProtocolVersion() {
 	defer runtimeProtocolState.Unlock()
 	runtimeProtocolState.connectionProtocol = 0
 	runtimeProtocolState.v2ProtocolFailures = 0
+	resetServerCapabilities()
 }
 
 func uploadProtocolVersion() int {
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	v2 "github.com/komari-monitor/komari-agent/protocol/v2"
+	"github.com/komari-monitor/komari-agent/ws"
+)
+
+// JSON-RPC 错误码
+const (
+	rpcCodeInvalidParams  = -32602
+	rpcCodeMethodNotFound = -32601
+	rpcCodeInternalError  = -32603
+	rpcCodeForbidden      = -32001
+	rpcCodeRateLimited    = -32002
+)
+
+// rpcContext 一次服务端指令的分发上下文
+type rpcContext struct {
+	Conn          *ws.SafeConn // 指令来源的 WebSocket 连接，POST 拉取模式下为 nil
+	Protocol      int          // 指令来源的协议版本
+	Method        string
+	EventID       string // 事件 ID，用于去重，可为空
+	Redeliverable bool   // 指令未被接受时来源会重新下发：拉取的事件与带 ID 的请求
+}
+
+// rpcHandler 处理一条指令，params 为原始参数
+type rpcHandler func(ctx *rpcContext, params interface{}) *v2.RPCError
+
+// rpcMiddleware 包装处理函数，route 为当前方法的注册信息
+type rpcMiddleware func(route *rpcRoute, next rpcHandler) rpcHandler
+
+// routeOptions 方法的公共策略
+type routeOptions struct {
+	RemoteControl bool                     // 属于远程控制能力，受 --disable-web-ssh 约束
+	RateLimit     int                      // 每分钟允许的调用次数，0 表示不限制
+	Audit         bool                     // 记录审计日志
+	OnDenied      func(params interface{}) // 被权限检查拒绝时的回调，用于告知面板
+	OnRateLimited func(params interface{}) // 被限流且来源不会重新下发时的回调，用于告知面板
+}
+
+// rpcRoute 已注册的方法
+type rpcRoute struct {
+	method  string
+	opts    routeOptions
+	limiter *rateLimiter
+	handler rpcHandler
+}
+
+// rpcRouter 按方法名分发服务端指令，并对所有方法应用相同的中间件
+type rpcRouter struct {
+	mu         sync.RWMutex
+	routes     map[string]*rpcRoute
+	middleware []rpcMiddleware
+}
+
+func newRPCRouter(middleware ...rpcMiddleware) *rpcRouter {
+	return &rpcRouter{routes: make(map[string]*rpcRoute), middleware: middleware}
+}
+
+// handle 以强类型参数注册方法，参数绑定失败时返回 invalid params 错误
+func handle[P any](r *rpcRouter, method string, opts routeOptions, fn func(ctx *rpcContext, p P) error) {
+	route := &rpcRoute{method: method, opts: opts}
+	if opts.RateLimit > 0 {
+		route.limiter = newRateLimiter(opts.RateLimit, time.Minute)
+	}
+	var h rpcHandler = func(ctx *rpcContext, raw interface{}) *v2.RPCError {
+		var p P
+		if raw != nil {
+			if err := v2.BindParams(raw, &p); err != nil {
+				return &v2.RPCError{Code: rpcCodeInvalidParams, Message: fmt.Sprintf("bad %s params: %v", method, err)}
+			}
+		}
+		if err := fn(ctx, p); err != nil {
+			return &v2.RPCError{Code: rpcCodeInternalError, Message: err.Error()}
+		}
+		return nil
+	}
+	// 先注册的中间件位于最外层
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](route, h)
+	}
+	route.handler = h
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[method] = route
+}
+
+// dispatch 分发一条指令
+func (r *rpcRouter) dispatch(ctx *rpcContext, params interface{}) *v2.RPCError {
+	r.mu.RLock()
+	route, ok := r.routes[ctx.Method]
+	r.mu.RUnlock()
+	if !ok {
+		return &v2.RPCError{Code: rpcCodeMethodNotFound, Message: "unknown method " + ctx.Method}
+	}
+	return route.handler(ctx, params)
+}
+
+// retryableRPCError 指令未被接受、服务端可以重新下发的错误：未知方法、参数错误与限流
+func retryableRPCError(rpcErr *v2.RPCError) bool {
+	if rpcErr == nil {
+		return false
+	}
+	switch rpcErr.Code {
+	case rpcCodeMethodNotFound, rpcCodeInvalidParams, rpcCodeRateLimited:
+		return true
+	}
+	return false
+}
+
+// recoverMiddleware 将处理函数中的 panic 转换为 RPC 错误
+func recoverMiddleware(route *rpcRoute, next rpcHandler) rpcHandler {
+	return func(ctx *rpcContext, params interface{}) (rpcErr *v2.RPCError) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("panic while handling %s: %v\n%s", route.method, r, debug.Stack())
+				rpcErr = &v2.RPCError{Code: rpcCodeInternalError, Message: fmt.Sprintf("internal error: %v", r)}
+			}
+		}()
+		return next(ctx, params)
+	}
+}
+
+// dedupeMiddleware 忽略已经处理过的事件。
+// 事件在处理前先登记以排除并发的重复投递；若因限流或参数错误未被接受，则撤销登记，
+// 使服务端重新下发时仍会执行
+func dedupeMiddleware(route *rpcRoute, next rpcHandler) rpcHandler {
+	return func(ctx *rpcContext, params interface{}) *v2.RPCError {
+		if !markV2EventSeen(ctx.EventID) {
+			return nil
+		}
+		rpcErr := next(ctx, params)
+		if retryableRPCError(rpcErr) {
+			forgetV2Event(ctx.EventID)
+		}
+		return rpcErr
+	}
+}
+
+// permissionMiddleware 在禁用远程控制时拒绝远程控制类方法
+func permissionMiddleware(route *rpcRoute, next rpcHandler) rpcHandler {
+	return func(ctx *rpcContext, params interface{}) *v2.RPCError {
+		if route.opts.RemoteControl && flags.DisableWebSsh {
+			if route.opts.OnDenied != nil {
+				route.opts.OnDenied(params)
+			}
+			return &v2.RPCError{Code: rpcCodeForbidden, Message: "remote control is disabled"}
+		}
+		return next(ctx, params)
+	}
+}
+
+// rateLimitMiddleware 限制方法的调用频率。
+// v1 消息与不带 ID 的 v2 通知被限流后不会重新下发，需通过 OnRateLimited 告知面板
+func rateLimitMiddleware(route *rpcRoute, next rpcHandler) rpcHandler {
+	return func(ctx *rpcContext, params interface{}) *v2.RPCError {
+		if route.limiter != nil && !route.limiter.allow(time.Now()) {
+			if !ctx.Redeliverable && route.opts.OnRateLimited != nil {
+				route.opts.OnRateLimited(params)
+			}
+			return &v2.RPCError{Code: rpcCodeRateLimited, Message: fmt.Sprintf("rate limit exceeded for %s", route.method)}
+		}
+		return next(ctx, params)
+	}
+}
+
+// auditMiddleware 记录需要审计的方法的调用与结果
+func auditMiddleware(route *rpcRoute, next rpcHandler) rpcHandler {
+	return func(ctx *rpcContext, params interface{}) *v2.RPCError {
+		rpcErr := next(ctx, params)
+		if route.opts.Audit || rpcErr != nil {
+			outcome := "ok"
+			if rpcErr != nil {
+				outcome = fmt.Sprintf("error %d: %s", rpcErr.Code, rpcErr.Message)
+			}
+			log.Printf("audit: method=%s event=%q protocol=v%d result=%s", route.method, ctx.EventID, ctx.Protocol, outcome)
+		}
+		return rpcErr
+	}
+}
+
+// rateLimiter 固定窗口计数的限流器
+type rateLimiter struct {
+	mu          sync.Mutex
+	limit       int
+	window      time.Duration
+	windowStart time.Time
+	count       int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window}
+}
+
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.windowStart) >= l.window {
+		l.windowStart = now
+		l.count = 0
+	}
+	if l.count >= l.limit {
+		return false
+	}
+	l.count++
+	return true
+}
+
+// v1Message v1 协议的服务端消息，各指令的字段混在同一个对象中
+type v1Message struct {
+	Message string `json:"message"`
+	// Terminal
+	TerminalId        string `json:"request_id,omitempty"`
+	TerminalSessionID string `json:"session_id,omitempty"`
+	// Remote Exec
+	ExecCommand string `json:"command,omitempty"`
+	ExecTaskID  string `json:"task_id,omitempty"`
+	// Ping
+	PingTaskID uint   `json:"ping_task_id,omitempty"`
+	PingType   string `json:"ping_type,omitempty"`
+	PingTarget string `json:"ping_target,omitempty"`
+	// Cert
+	CertTaskID string `json:"cert_task_id,omitempty"`
+	CertTarget string `json:"cert_target,omitempty"`
+	pingOptions
+}
+
+// translateV1 将 v1 消息转换为对应的 v2 方法与参数，无法识别时返回空方法名
+func translateV1(m v1Message) (string, interface{}) {
+	switch {
+	case m.Message == "terminal" || m.TerminalId != "":
+		return v2.MethodAgentTerminal, map[string]interface{}{"request_id": m.TerminalId, "session_id": m.TerminalSessionID}
+	case m.Message == "exec":
+		return v2.MethodAgentExec, execParams{TaskID: m.ExecTaskID, Command: m.ExecCommand}
+	case m.Message == "cert":
+		return v2.MethodAgentCert, certParams{TaskID: m.CertTaskID, Target: m.CertTarget, pingOptions: m.pingOptions}
+	case m.Message == "ping" || m.PingTaskID != 0 || m.PingType != "" || m.PingTarget != "":
+		return v2.MethodAgentPing, pingParams{TaskID: m.PingTaskID, Type: m.PingType, Target: m.PingTarget, pingOptions: m.pingOptions}
+	}
+	return "", nil
+}
+
+// decodeV1Message 解析 v1 消息
+func decodeV1Message(raw []byte) (v1Message, error) {
+	var m v1Message
+	err := json.Unmarshal(raw, &m)
+	return m, err
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	v2 "github.com/komari-monitor/komari-agent/protocol/v2"
+)
+
+func TestRouterBindsTypedParams(t *testing.T) {
+	r := newRPCRouter(recoverMiddleware)
+	var got execParams
+	handle(r, "test.exec", routeOptions{}, func(ctx *rpcContext, p execParams) error {
+		got = p
+		return nil
+	})
+
+	if err := r.dispatch(&rpcContext{Method: "test.exec"}, map[string]interface{}{"task_id": "7", "command": "uptime"}); err != nil {
+		t.Fatalf("dispatch: %+v", err)
+	}
+	if got.TaskID != "7" || got.Command != "uptime" {
+		t.Fatalf("unexpected params %+v", got)
+	}
+	if err := r.dispatch(&rpcContext{Method: "test.exec"}, map[string]interface{}{"task_id": 7}); err == nil || err.Code != rpcCodeInvalidParams {
+		t.Fatalf("expected invalid params, got %+v", err)
+	}
+	if err := r.dispatch(&rpcContext{Method: "test.missing"}, nil); err == nil || err.Code != rpcCodeMethodNotFound {
+		t.Fatalf("expected method not found, got %+v", err)
+	}
+}
+
+func TestRouterMiddleware(t *testing.T) {
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+
+	r := newRPCRouter(recoverMiddleware, dedupeMiddleware, auditMiddleware, permissionMiddleware, rateLimitMiddleware)
+	calls := 0
+	handle(r, "test.limited", routeOptions{RateLimit: 2}, func(ctx *rpcContext, p struct{}) error {
+		calls++
+		return nil
+	})
+	handle(r, "test.panic", routeOptions{}, func(ctx *rpcContext, p struct{}) error {
+		panic("boom")
+	})
+	denied := false
+	handle(r, "test.control", routeOptions{RemoteControl: true, OnDenied: func(interface{}) { denied = true }}, func(ctx *rpcContext, p struct{}) error {
+		calls++
+		return nil
+	})
+	handle(r, "test.fail", routeOptions{}, func(ctx *rpcContext, p struct{}) error {
+		return errors.New("failed")
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := r.dispatch(&rpcContext{Method: "test.limited"}, nil); err != nil {
+			t.Fatalf("call %d: %+v", i, err)
+		}
+	}
+	if err := r.dispatch(&rpcContext{Method: "test.limited"}, nil); err == nil || err.Code != rpcCodeRateLimited {
+		t.Fatalf("expected rate limit, got %+v", err)
+	}
+
+	if err := r.dispatch(&rpcContext{Method: "test.panic"}, nil); err == nil || err.Code != rpcCodeInternalError {
+		t.Fatalf("expected panic to become an internal error, got %+v", err)
+	}
+	if err := r.dispatch(&rpcContext{Method: "test.fail"}, nil); err == nil || err.Message != "failed" {
+		t.Fatalf("expected handler error, got %+v", err)
+	}
+
+	flags.DisableWebSsh = true
+	if err := r.dispatch(&rpcContext{Method: "test.control"}, nil); err == nil || err.Code != rpcCodeForbidden || !denied {
+		t.Fatalf("expected remote control to be denied, got %+v (denied=%v)", err, denied)
+	}
+
+	flags.DisableWebSsh = false
+	calls = 0
+	eventID := fmt.Sprintf("router-test-%d", time.Now().UnixNano())
+	for i := 0; i < 2; i++ {
+		if err := r.dispatch(&rpcContext{Method: "test.control", EventID: eventID}, nil); err != nil {
+			t.Fatalf("dispatch event: %+v", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected duplicate event to be ignored, handler ran %d times", calls)
+	}
+}
+
+func TestTranslateV1(t *testing.T) {
+	cases := []struct {
+		msg    v1Message
+		method string
+	}{
+		{v1Message{Message: "exec", ExecTaskID: "1", ExecCommand: "ls"}, v2.MethodAgentExec},
+		{v1Message{TerminalId: "req"}, v2.MethodAgentTerminal},
+		{v1Message{Message: "cert", CertTaskID: "2", CertTarget: "example.com"}, v2.MethodAgentCert},
+		{v1Message{PingTaskID: 3, PingType: "tcp", PingTarget: "1.1.1.1:53"}, v2.MethodAgentPing},
+		{v1Message{Message: "hello"}, ""},
+	}
+	for _, c := range cases {
+		if method, _ := translateV1(c.msg); method != c.method {
+			t.Errorf("translateV1(%+v) = %q, want %q", c.msg, method, c.method)
+		}
+	}
+
+	_, params := translateV1(v1Message{Message: "cert", CertTaskID: "2", CertTarget: "example.com", pingOptions: pingOptions{IPVersion: 6}})
+	var p certParams
+	if err := v2.BindParams(params, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.TaskID != "2" || p.Target != "example.com" || p.IPVersion != 6 {
+		t.Fatalf("unexpected cert params %+v", p)
+	}
+}
+
+func TestRouterRedeliveredEventsRunAfterRejection(t *testing.T) {
+	r := newRPCRouter(recoverMiddleware, dedupeMiddleware, auditMiddleware, permissionMiddleware, rateLimitMiddleware)
+	calls := 0
+	handle(r, "test.limited", routeOptions{RateLimit: 1}, func(ctx *rpcContext, p execParams) error {
+		calls++
+		return nil
+	})
+	event := func(id string) *rpcContext {
+		return &rpcContext{Method: "test.limited", EventID: fmt.Sprintf("%s-%d", id, time.Now().UnixNano())}
+	}
+
+	// 限流：被限流的事件不确认，窗口重置后重新下发时执行
+	if !processEvent(r, event("first"), nil) {
+		t.Fatal("expected first event to be acked")
+	}
+	limited := event("limited")
+	if processEvent(r, limited, nil) {
+		t.Fatal("rate-limited event should not be acked")
+	}
+	r.routes["test.limited"].limiter = newRateLimiter(1, time.Minute)
+	if !processEvent(r, limited, nil) || calls != 2 {
+		t.Fatalf("expected redelivered rate-limited event to run, handler ran %d times", calls)
+	}
+
+	// 参数错误：不确认，修正后重新下发时执行而不是被当作重复事件
+	r.routes["test.limited"].limiter = nil
+	invalid := event("invalid")
+	if processEvent(r, invalid, map[string]interface{}{"task_id": 7}) {
+		t.Fatal("event with invalid params should not be acked")
+	}
+	if !processEvent(r, invalid, map[string]interface{}{"task_id": "7"}) || calls != 3 {
+		t.Fatalf("expected redelivered event to run, handler ran %d times", calls)
+	}
+	if !processEvent(r, invalid, map[string]interface{}{"task_id": "7"}) || calls != 3 {
+		t.Fatalf("expected accepted event to be deduplicated, handler ran %d times", calls)
+	}
+}
+
+func TestRouterReportsRateLimitToSourcesThatDoNotRedeliver(t *testing.T) {
+	r := newRPCRouter(recoverMiddleware, dedupeMiddleware, auditMiddleware, permissionMiddleware, rateLimitMiddleware)
+	var limited []string
+	handle(r, "test.limited", routeOptions{
+		RateLimit: 1,
+		OnRateLimited: func(params interface{}) {
+			var p execParams
+			if v2.BindParams(params, &p) == nil {
+				limited = append(limited, p.TaskID)
+			}
+		},
+	}, func(ctx *rpcContext, p execParams) error {
+		return nil
+	})
+	params := func(id string) interface{} { return map[string]interface{}{"task_id": id} }
+
+	if err := r.dispatch(&rpcContext{Method: "test.limited"}, params("1")); err != nil {
+		t.Fatalf("first call: %+v", err)
+	}
+	// 拉取的事件与带 ID 的请求会被重新下发，不需要告知面板
+	if err := r.dispatch(&rpcContext{Method: "test.limited", Redeliverable: true}, params("2")); err == nil || err.Code != rpcCodeRateLimited {
+		t.Fatalf("expected rate limit, got %+v", err)
+	}
+	// v1 消息与不带 ID 的通知不会重新下发，需要上报失败
+	if err := r.dispatch(&rpcContext{Method: "test.limited"}, params("3")); err == nil || err.Code != rpcCodeRateLimited {
+		t.Fatalf("expected rate limit, got %+v", err)
+	}
+	if len(limited) != 1 || limited[0] != "3" {
+		t.Fatalf("expected only the non-redeliverable call to be reported, got %v", limited)
+	}
+}
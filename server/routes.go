@@ -0,0 +1,145 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	v2 "github.com/komari-monitor/komari-agent/protocol/v2"
+	"github.com/komari-monitor/komari-agent/terminal"
+)
+
+type execParams struct {
+	TaskID  string `json:"task_id"`
+	Command string `json:"command"`
+}
+
+type pingParams struct {
+	TaskID uint   `json:"ping_task_id"`
+	Type   string `json:"ping_type"`
+	Target string `json:"ping_target"`
+	pingOptions
+}
+
+type certParams struct {
+	TaskID string `json:"task_id"`
+	Target string `json:"target"`
+	pingOptions
+}
+
+type bandwidthParams struct {
+	TaskID string `json:"task_id"`
+	bandwidthOptions
+}
+
+type terminalSessionsParams struct {
+	TaskID    string `json:"task_id"`
+	Action    string `json:"action"`
+	SessionID string `json:"session_id"`
+}
+
+type tunnelParams struct {
+	RequestID string `json:"request_id"`
+	Target    string `json:"target"`
+}
+
+// defaultRouter 处理服务端下发的所有指令。
+// 中间件依次为：panic 恢复、事件去重、审计日志、权限检查、限流
+var defaultRouter = newDefaultRouter()
+
+func newDefaultRouter() *rpcRouter {
+	r := newRPCRouter(recoverMiddleware, dedupeMiddleware, auditMiddleware, permissionMiddleware, rateLimitMiddleware)
+
+	handle(r, v2.MethodAgentExec, routeOptions{
+		RemoteControl: true,
+		RateLimit:     60,
+		Audit:         true,
+		OnDenied: func(params interface{}) {
+			var p execParams
+			if v2.BindParams(params, &p) == nil && p.TaskID != "" {
+				go uploadTaskResult(p.TaskID, "Remote control is disabled.", -1, time.Now())
+			}
+		},
+		OnRateLimited: func(params interface{}) {
+			var p execParams
+			if v2.BindParams(params, &p) == nil && p.TaskID != "" {
+				go uploadTaskResult(p.TaskID, "Rate limit exceeded.", -1, time.Now())
+			}
+		},
+	}, func(ctx *rpcContext, p execParams) error {
+		go NewTask(p.TaskID, p.Command)
+		return nil
+	})
+	handle(r, v2.MethodAgentPing, routeOptions{}, func(ctx *rpcContext, p pingParams) error {
+		go NewPingTask(ctx.Conn, ctx.Protocol, p.TaskID, p.Type, p.Target, p.pingOptions)
+		return nil
+	})
+	handle(r, v2.MethodAgentCert, routeOptions{}, func(ctx *rpcContext, p certParams) error {
+		go NewCertTask(p.TaskID, p.Target, p.pingOptions)
+		return nil
+	})
+	handle(r, v2.MethodAgentBandwidth, routeOptions{
+		RateLimit: 10,
+		Audit:     true,
+		OnRateLimited: func(params interface{}) {
+			var p bandwidthParams
+			if v2.BindParams(params, &p) == nil && p.TaskID != "" {
+				go uploadTaskResult(p.TaskID, "Rate limit exceeded.", -1, time.Now())
+			}
+		},
+	}, func(ctx *rpcContext, p bandwidthParams) error {
+		go NewBandwidthTask(p.TaskID, p.bandwidthOptions)
+		return nil
+	})
+	// 终端与端口转发被拒绝或限流时仍建立专用连接，由其在连接上向面板说明原因
+	handle(r, v2.MethodAgentTerminal, routeOptions{
+		RemoteControl: true,
+		RateLimit:     30,
+		Audit:         true,
+		OnDenied: func(params interface{}) {
+			var p terminal.Options
+			if v2.BindParams(params, &p) == nil {
+				go establishTerminalConnection(flags.Token, flags.Endpoint, p)
+			}
+		},
+		OnRateLimited: func(params interface{}) {
+			var p terminal.Options
+			if v2.BindParams(params, &p) == nil {
+				go rejectTerminalConnection(flags.Token, flags.Endpoint, p.RequestID, "rate limit exceeded")
+			}
+		},
+	}, func(ctx *rpcContext, p terminal.Options) error {
+		go establishTerminalConnection(flags.Token, flags.Endpoint, p)
+		return nil
+	})
+	handle(r, v2.MethodAgentTerminalSessions, routeOptions{RemoteControl: true, Audit: true}, func(ctx *rpcContext, p terminalSessionsParams) error {
+		go NewTerminalSessionsTask(p.TaskID, p.Action, p.SessionID)
+		return nil
+	})
+	handle(r, v2.MethodAgentTunnelOpen, routeOptions{
+		RemoteControl: true,
+		RateLimit:     30,
+		Audit:         true,
+		OnDenied: func(params interface{}) {
+			var p tunnelParams
+			if v2.BindParams(params, &p) == nil {
+				go NewTunnel(p.RequestID, p.Target)
+			}
+		},
+		OnRateLimited: func(params interface{}) {
+			var p tunnelParams
+			if v2.BindParams(params, &p) == nil {
+				go rejectTunnel(p.RequestID, "rate limit exceeded")
+			}
+		},
+	}, func(ctx *rpcContext, p tunnelParams) error {
+		go NewTunnel(p.RequestID, p.Target)
+		return nil
+	})
+	logMessage := func(ctx *rpcContext, p interface{}) error {
+		log.Printf("received v2 %s: %+v", ctx.Method, p)
+		return nil
+	}
+	handle(r, v2.MethodAgentMessage, routeOptions{}, logMessage)
+	handle(r, v2.MethodAgentEvent, routeOptions{}, logMessage)
+	return r
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	v2 "github.com/komari-monitor/komari-agent/protocol/v2"
+	"github.com/komari-monitor/komari-agent/ws"
+)
+
+// v2CallTimeout 等待服务端响应 v2 请求的时间
+const v2CallTimeout = 10 * time.Second
+
+var errV2CallTimeout = errors.New("timed out waiting for v2 response")
+
+// activeV2Conn 当前使用 v2 协议的主 WebSocket 连接，未连接时为 nil
+var activeV2Conn struct {
+	sync.RWMutex
+	conn *ws.SafeConn
+}
+
+func setActiveV2Conn(conn *ws.SafeConn) {
+	activeV2Conn.Lock()
+	defer activeV2Conn.Unlock()
+	activeV2Conn.conn = conn
+}
+
+// clearActiveV2Conn 仅当 conn 仍是当前连接时清除，避免覆盖新建立的连接
+func clearActiveV2Conn(conn *ws.SafeConn) {
+	activeV2Conn.Lock()
+	defer activeV2Conn.Unlock()
+	if activeV2Conn.conn == conn {
+		activeV2Conn.conn = nil
+	}
+}
+
+func currentV2Conn() *ws.SafeConn {
+	activeV2Conn.RLock()
+	defer activeV2Conn.RUnlock()
+	return activeV2Conn.conn
+}
+
+// v2Calls 等待响应的 v2 请求，按请求 ID 索引
+var v2Calls struct {
+	sync.Mutex
+	seq     uint64
+	pending map[string]chan *v2.Response
+}
+
+// callV2 通过 WebSocket 发送 v2 请求并等待服务端响应
+func callV2(conn *ws.SafeConn, method string, params interface{}, timeout time.Duration) (*v2.Response, error) {
+	v2Calls.Lock()
+	v2Calls.seq++
+	id := fmt.Sprintf("%s-%d-%d", method, time.Now().UnixNano(), v2Calls.seq)
+	if v2Calls.pending == nil {
+		v2Calls.pending = make(map[string]chan *v2.Response)
+	}
+	ch := make(chan *v2.Response, 1)
+	v2Calls.pending[id] = ch
+	v2Calls.Unlock()
+	defer func() {
+		v2Calls.Lock()
+		delete(v2Calls.pending, id)
+		v2Calls.Unlock()
+	}()
+
+	if err := conn.WriteMessage(websocket.TextMessage, v2.NewRequest(id, method, params)); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp, fmt.Errorf("v2 %s failed: %d %s", method, resp.Error.Code, resp.Error.Message)
+		}
+		return resp, nil
+	case <-timer.C:
+		return nil, errV2CallTimeout
+	}
+}
+
+// resolveV2Call 将服务端响应交给对应的等待者，没有匹配的请求时返回 false
+func resolveV2Call(resp *v2.Response) bool {
+	if resp == nil || resp.ID == nil {
+		return false
+	}
+	id := fmt.Sprint(resp.ID)
+	v2Calls.Lock()
+	ch, ok := v2Calls.pending[id]
+	delete(v2Calls.pending, id)
+	v2Calls.Unlock()
+	if ok {
+		ch <- resp
+	}
+	return ok
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	v2 "github.com/komari-monitor/komari-agent/protocol/v2"
+	"github.com/komari-monitor/komari-agent/ws"
+)
+
+// startV2Peer 启动一个模拟面板的 v2 WebSocket 服务端，handle 处理收到的每个请求并返回响应（nil 表示不响应）
+func startV2Peer(t *testing.T, handle func(req v2.Request) *v2.Response) *ws.SafeConn {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, raw, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req v2.Request
+			if err := json.Unmarshal(raw, &req); err != nil {
+				continue
+			}
+			if resp := handle(req); resp != nil {
+				conn.WriteJSON(resp)
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	raw, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	conn := ws.NewSafeConn(raw)
+	done := make(chan struct{})
+	go handleWebSocketMessages(conn, 2, done, nil)
+	t.Cleanup(func() {
+		conn.Close()
+		<-done
+	})
+	// 等待读协程登记为当前连接
+	for i := 0; i < 100 && currentV2Conn() != conn; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return conn
+}
+
+func TestCallV2ReceivesAck(t *testing.T) {
+	conn := startV2Peer(t, func(req v2.Request) *v2.Response {
+		return &v2.Response{JSONRPC: v2.Version, ID: req.ID, Result: map[string]string{"status": "ok"}}
+	})
+
+	resp, err := callV2(conn, v2.MethodAgentTaskResult, map[string]string{"task_id": "1"}, time.Second)
+	if err != nil {
+		t.Fatalf("callV2: %v", err)
+	}
+	var result map[string]string
+	if err := v2.BindResult(resp.Result, &result); err != nil || result["status"] != "ok" {
+		t.Fatalf("unexpected result %v (%v)", resp.Result, err)
+	}
+}
+
+func TestCallV2ReportsErrorsAndTimeouts(t *testing.T) {
+	conn := startV2Peer(t, func(req v2.Request) *v2.Response {
+		if req.Method == "agent.silent" {
+			return nil
+		}
+		return &v2.Response{JSONRPC: v2.Version, ID: req.ID, Error: &v2.RPCError{Code: -32601, Message: "method not found"}}
+	})
+
+	if _, err := callV2(conn, v2.MethodAgentTaskResult, nil, time.Second); err == nil || !strings.Contains(err.Error(), "method not found") {
+		t.Fatalf("expected rpc error, got %v", err)
+	}
+	if _, err := callV2(conn, "agent.silent", nil, 50*time.Millisecond); err != errV2CallTimeout {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+}
+
+func TestUploadTaskResultPrefersWebSocket(t *testing.T) {
+	var httpHits atomic.Int32
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpHits.Add(1)
+	}))
+	defer httpSrv.Close()
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+	flags.Endpoint = httpSrv.URL
+	flags.MaxRetries = 0
+
+	received := make(chan map[string]interface{}, 1)
+	startV2Peer(t, func(req v2.Request) *v2.Response {
+		var params map[string]interface{}
+		v2.BindParams(req.Params, &params)
+		received <- params
+		return &v2.Response{JSONRPC: v2.Version, ID: req.ID, Result: map[string]string{"status": "ok"}}
+	})
+	setServerCapabilities([]string{v2.MethodAgentTaskResult})
+	t.Cleanup(resetServerCapabilities)
+
+	uploadTaskResult("task-1", "done", 0, time.Now())
+	select {
+	case params := <-received:
+		if params["task_id"] != "task-1" || params["result"] != "done" {
+			t.Fatalf("unexpected params %v", params)
+		}
+	default:
+		t.Fatal("expected task result over WebSocket")
+	}
+	if httpHits.Load() != 0 {
+		t.Fatal("HTTP endpoint should only be used as a fallback")
+	}
+
+	// 服务端不支持时回退到 HTTP
+	resetServerCapabilities()
+	uploadTaskResult("task-2", "done", 0, time.Now())
+	if httpHits.Load() != 1 {
+		t.Fatalf("expected HTTP fallback, got %d requests", httpHits.Load())
+	}
+}
+
+func TestHandleWebSocketMessagesRunsOnReadyAfterRegistration(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+	raw, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	conn := ws.NewSafeConn(raw)
+
+	ready := make(chan *ws.SafeConn, 1)
+	done := make(chan struct{})
+	go handleWebSocketMessages(conn, 2, done, func() { ready <- currentV2Conn() })
+	defer func() {
+		conn.Close()
+		<-done
+	}()
+	select {
+	case active := <-ready:
+		if active != conn {
+			t.Fatal("onReady ran before the connection was registered")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("onReady was not called")
+	}
+}
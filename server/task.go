@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net"
@@ -14,12 +15,12 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/komari-monitor/komari-agent/dnsresolver"
 	v2 "github.com/komari-monitor/komari-agent/protocol/v2"
 	"github.com/komari-monitor/komari-agent/ws"
-	ping "github.com/prometheus-community/pro-bing"
 )
 
 func NewTask(task_id, command string) {
@@ -119,6 +120,15 @@ func uploadTaskResult(taskID, result string, exitCode int, finishedAt time.Time)
 		"finished_at": finishedAt,
 	}
 
+	// 优先通过当前的 v2 WebSocket 发送并等待服务端确认，失败时回退到 HTTP 上报
+	if conn := currentV2Conn(); conn != nil && serverSupports(v2.MethodAgentTaskResult) {
+		if _, err := callV2(conn, v2.MethodAgentTaskResult, payload, v2CallTimeout); err == nil {
+			return
+		} else {
+			log.Printf("Failed to send task result over WebSocket, falling back to HTTP: %v", err)
+		}
+	}
+
 	jsonData, _ := json.Marshal(payload)
 	endpoint := strings.TrimSuffix(flags.Endpoint, "/") + "/api/clients/task/result?token=" + flags.Token
 
@@ -155,8 +165,22 @@ func uploadTaskResult(taskID, result string, exitCode int, finishedAt time.Time)
 
 // resolveIP 解析域名到 IP 地址，排除 DNS 查询时间
 func resolveIP(target string) (string, error) {
-	// 如果已经是 IP 地址，直接返回
+	return resolveIPWithOptions(target, pingOptions{})
+}
+
+// resolveIPWithOptions 按探测参数解析目标地址：
+// 指定 ip_version（或 source_ip 隐含的地址族）时只保留对应地址族，
+// 否则按 PreferIPVersion 配置排序后取第一个
+func resolveIPWithOptions(target string, opts pingOptions) (string, error) {
+	family, err := opts.family()
+	if err != nil {
+		return "", err
+	}
+	// 如果已是 IP 地址，直接返回
 	if ip := net.ParseIP(target); ip != nil {
+		if !ipMatchesFamily(ip, family) {
+			return "", fmt.Errorf("target %s is not an IPv%d address", target, family)
+		}
 		return target, nil
 	}
 	// 解析域名到 IP
@@ -164,43 +188,55 @@ func resolveIP(target string) (string, error) {
 	if err != nil || len(addrs) == 0 {
 		return "", errors.New("failed to resolve target")
 	}
-	return addrs[0], nil // 返回第一个解析的 IP
-}
-
-func icmpPing(target string, timeout time.Duration) (int64, error) {
-	host, _, err := net.SplitHostPort(target)
-	if err != nil {
-		host = target
+	if family == 0 {
+		if flags.PreferIPVersion != "" {
+			dnsresolver.SortIPsByPreference(addrs, flags.PreferIPVersion)
+		}
+		return addrs[0], nil
 	}
-	// For ICMP, we only need the host/IP, port is irrelevant.
-	// If the host is an IPv6 literal, it might be wrapped in brackets.
-	host = strings.Trim(host, "[]")
-
-	// 先解析 IP 地址
-	ip, err := resolveIP(host)
-	if err != nil {
-		return -1, err
+	for _, addr := range addrs {
+		if ipMatchesFamily(net.ParseIP(addr), family) {
+			return addr, nil
+		}
 	}
+	return "", fmt.Errorf("no IPv%d address found for %s", family, target)
+}
 
-	pinger, err := ping.NewPinger(ip)
-	if err != nil {
-		return -1, err
+func ipMatchesFamily(ip net.IP, family int) bool {
+	switch family {
+	case 4:
+		return ip != nil && ip.To4() != nil
+	case 6:
+		return ip != nil && ip.To4() == nil
 	}
-	pinger.Count = 1
-	pinger.Timeout = timeout
-	pinger.SetPrivileged(true)
-	err = pinger.Run()
-	if err != nil {
-		return -1, err
+	return ip != nil
+}
+
+// newProbeDialer 按探测参数构造拨号器，绑定源地址或出口网卡
+func newProbeDialer(timeout time.Duration, opts pingOptions, targetIP string) (*net.Dialer, error) {
+	d := &net.Dialer{Timeout: timeout}
+	if opts.SourceIP != "" {
+		ip := net.ParseIP(opts.SourceIP)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source_ip %q", opts.SourceIP)
+		}
+		d.LocalAddr = &net.TCPAddr{IP: ip}
 	}
-	stats := pinger.Statistics()
-	if stats.PacketsRecv == 0 {
-		return -1, errors.New("no packets received")
+	if opts.Interface != "" {
+		if err := bindToInterface(d, opts.Interface, targetIP); err != nil {
+			return nil, err
+		}
 	}
-	return stats.AvgRtt.Milliseconds(), nil
+	return d, nil
 }
 
 func tcpPing(target string, timeout time.Duration) (int64, error) {
+	result, err := tcpProbe(target, timeout, pingOptions{})
+	return result.Latency, err
+}
+
+func tcpProbe(target string, timeout time.Duration, opts pingOptions) (probeResult, error) {
+	result := probeResult{Latency: -1, Method: "tcp"}
 	host, port, err := net.SplitHostPort(target)
 	if err != nil {
 		// No port, assume port 80
@@ -211,22 +247,34 @@ func tcpPing(target string, timeout time.Duration) (int64, error) {
 	// If the host is an IPv6 literal, it might be wrapped in brackets.
 	host = strings.Trim(host, "[]")
 
-	ip, err := resolveIP(host)
+	ip, err := resolveIPWithOptions(host, opts)
 	if err != nil {
-		return -1, err
+		return result, err
+	}
+	dialer, err := newProbeDialer(timeout, opts, ip)
+	if err != nil {
+		return result, err
 	}
 
 	targetAddr := net.JoinHostPort(ip, port)
+	result.Address = targetAddr
 	start := time.Now()
-	conn, err := net.DialTimeout("tcp", targetAddr, timeout)
+	conn, err := dialer.Dial("tcp", targetAddr)
 	if err != nil {
-		return -1, err
+		return result, err
 	}
 	defer conn.Close()
-	return time.Since(start).Milliseconds(), nil
+	result.Latency = time.Since(start).Milliseconds()
+	return result, nil
 }
 
 func httpPing(target string, timeout time.Duration) (int64, error) {
+	result, err := httpProbe(target, timeout, pingOptions{})
+	return result.Latency, err
+}
+
+func httpProbe(target string, timeout time.Duration, opts pingOptions) (probeResult, error) {
+	result := probeResult{Latency: -1, Method: "http"}
 	// Handle raw IPv6 address for URL
 	if strings.Contains(target, ":") && !strings.Contains(target, "[") {
 		// check if it's a valid IP to avoid wrapping hostnames
@@ -239,6 +287,7 @@ func httpPing(target string, timeout time.Duration) (int64, error) {
 		target = "http://" + target
 	}
 
+	var addrMu sync.Mutex
 	transport := &http.Transport{
 		DisableKeepAlives: true,
 		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
@@ -247,11 +296,19 @@ func httpPing(target string, timeout time.Duration) (int64, error) {
 			if err != nil {
 				return nil, err
 			}
-			ip, err := resolveIP(host)
+			ip, err := resolveIPWithOptions(host, opts)
 			if err != nil {
 				return nil, err
 			}
-			return net.DialTimeout(network, net.JoinHostPort(ip, port), timeout)
+			dialer, err := newProbeDialer(timeout, opts, ip)
+			if err != nil {
+				return nil, err
+			}
+			targetAddr := net.JoinHostPort(ip, port)
+			addrMu.Lock()
+			result.Address = targetAddr
+			addrMu.Unlock()
+			return dialer.DialContext(ctx, network, targetAddr)
 		},
 	}
 	defer transport.CloseIdleConnections()
@@ -263,44 +320,98 @@ func httpPing(target string, timeout time.Duration) (int64, error) {
 	start := time.Now()
 	resp, err := client.Get(target)
 	latency := time.Since(start).Milliseconds()
+	addrMu.Lock()
+	defer addrMu.Unlock()
 	if err != nil {
-		return -1, err
+		return result, err
 	}
 	defer resp.Body.Close()
+	result.Latency = latency
 	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
-		return latency, nil
+		return result, nil
+	}
+	return result, errors.New("http status not ok")
+}
+
+// pingOptions 探测任务的可选参数
+type pingOptions struct {
+	SourceIP  string `json:"source_ip,omitempty"`  // 源地址
+	Interface string `json:"interface,omitempty"`  // 出口网卡，Linux 下使用 SO_BINDTODEVICE
+	IPVersion int    `json:"ip_version,omitempty"` // 强制地址族：4 或 6
+	certOptions
+}
+
+// family 返回探测应使用的地址族，0 表示不限制
+func (o pingOptions) family() (int, error) {
+	switch o.IPVersion {
+	case 0:
+	case 4, 6:
+		return o.IPVersion, nil
+	default:
+		return 0, fmt.Errorf("invalid ip_version %d: expected 4 or 6", o.IPVersion)
+	}
+	// 未指定时由源地址推断
+	if ip := net.ParseIP(o.SourceIP); ip != nil {
+		if ip.To4() != nil {
+			return 4, nil
+		}
+		return 6, nil
 	}
-	return latency, errors.New("http status not ok")
+	return 0, nil
 }
 
-func NewPingTask(conn *ws.SafeConn, protocolVersion int, taskID uint, pingType, pingTarget string) {
+// probeResult 单次探测结果
+type probeResult struct {
+	Latency int64  // 毫秒，失败时为 -1
+	Method  string // 实际使用的探测方式
+	Address string // 实际探测的地址
+}
+
+func NewPingTask(conn *ws.SafeConn, protocolVersion int, taskID uint, pingType, pingTarget string, opts pingOptions) {
 	if taskID == 0 {
 		log.Printf("Invalid task ID: %d", taskID)
 		return
 	}
 	var err error = nil
 	var latency int64
+	var detail interface{}
 	pingResult := -1
+	method := pingType
+	address := ""
 	timeout := 3 * time.Second           // 默认超时时间
 	const highLatencyThreshold = 1000    // ms 阈值
 	const retryDropThresholdTcping = 800 // ms 重试中延迟降低超过此值则基本认为发生重传
 	// 800ms = SYN/SYN-ACK 首次超时重传 1000ms - 防误判容许 200ms 延迟抖动
 
 	measure := func() (int64, error) {
+		var result probeResult
+		var err error
 		switch pingType {
 		case "icmp":
-			return icmpPing(pingTarget, timeout)
+			result, err = icmpProbe(pingTarget, timeout, opts)
 		case "tcp":
-			return tcpPing(pingTarget, timeout)
+			result, err = tcpProbe(pingTarget, timeout, opts)
 		case "http":
-			return httpPing(pingTarget, timeout)
+			result, err = httpProbe(pingTarget, timeout, opts)
 		default:
 			return -1, errors.New("unsupported ping type")
 		}
+		method = result.Method
+		if result.Address != "" {
+			address = result.Address
+		}
+		return result.Latency, err
 	}
 	PingHighLatencyRetries := 3
-	// 首次测量
-	if latency, err = measure(); err == nil {
+	if pingType == "cert" {
+		// 证书检查的结果值为叶子证书剩余天数（过期时为 0），完整证书链放在 detail 中
+		var inspection *certInspection
+		if inspection, err = inspectCertificate(pingTarget, opts, 10*time.Second); err == nil {
+			latency = certPingValue(inspection)
+			address = inspection.Address
+			detail = inspection
+		}
+	} else if latency, err = measure(); err == nil { // 首次测量
 		firstLatency := latency
 		if latency > int64(highLatencyThreshold) && PingHighLatencyRetries > 0 {
 			attempts := PingHighLatencyRetries
@@ -331,23 +442,40 @@ func NewPingTask(conn *ws.SafeConn, protocolVersion int, taskID uint, pingType,
 	} else {
 		pingResult = int(latency)
 	}
-	finishedAt := time.Now()
+	result := v2.PingResult{
+		TaskID:     taskID,
+		PingType:   pingType,
+		PingMethod: method,
+		Address:    address,
+		Value:      pingResult,
+		FinishedAt: time.Now(),
+		Detail:     detail,
+	}
 	payload := map[string]interface{}{
 		"type":        "ping_result",
-		"task_id":     taskID,
-		"ping_type":   pingType,
-		"value":       pingResult,
-		"finished_at": finishedAt,
+		"task_id":     result.TaskID,
+		"ping_type":   result.PingType,
+		"ping_method": result.PingMethod,
+		"address":     result.Address,
+		"value":       result.Value,
+		"finished_at": result.FinishedAt,
+	}
+	if detail != nil {
+		payload["detail"] = detail
 	}
 	var wsPayload interface{} = payload
 	if protocolVersion >= 2 {
-		wsPayload = v2.BuildPingResultPayload(taskID, pingType, pingResult, finishedAt)
+		wsPayload = v2.BuildPingResultPayload(result)
 	}
 	// https://github.com/komari-monitor/komari/commit/eb87a4fc330b7d1c407fa4ff70177615a4f50a1f
 	// -1 代表丢包，服务端计算
 	//if pingResult == -1 {
 	//	return
 	//}
+	if protocolVersion >= 2 && serverSupports(v2.MethodAgentPingResults) {
+		defaultPingBatcher.add(conn, result)
+		return
+	}
 	if conn == nil {
 		if protocolVersion >= 2 {
 			if err := postV2RPC(wsPayload); err != nil {
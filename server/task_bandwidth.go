@@ -0,0 +1,466 @@
+package server
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	bandwidthDefaultDuration = 10.0 // 秒，单个方向
+	bandwidthMaxDuration     = 60.0
+	bandwidthDefaultStreams  = 1
+	bandwidthMaxStreams      = 16
+	bandwidthDefaultTimeout  = 60  // 秒，服务端监听时长
+	bandwidthMaxTimeout      = 600 // 秒
+	bandwidthMaxConns        = 64  // 服务端同时处理的最大连接数
+	bandwidthChunkSize       = 128 << 10
+)
+
+// bandwidthOptions 带宽测试参数
+type bandwidthOptions struct {
+	Mode      string  `json:"mode"`                // server 或 client
+	Target    string  `json:"target,omitempty"`    // client：测试服务端 host:port
+	Listen    string  `json:"listen,omitempty"`    // server：监听地址，必须显式指定 IP，如 192.0.2.10:5201
+	Key       string  `json:"key"`                 // 双方共享的测试密钥
+	Direction string  `json:"direction,omitempty"` // upload、download 或 both，默认 both
+	Duration  float64 `json:"duration,omitempty"`  // 单个方向的测试时长，单位秒
+	Streams   int     `json:"streams,omitempty"`   // 并行连接数
+	MaxBytes  int64   `json:"max_bytes,omitempty"` // 单个方向的字节上限，0 表示不限制
+	Timeout   int     `json:"timeout,omitempty"`   // server：监听时长，单位秒
+	pingOptions
+}
+
+// bandwidthHello 客户端在每条连接建立后发送的握手
+type bandwidthHello struct {
+	Key       string `json:"key"`
+	Direction string `json:"direction"`
+	Duration  int64  `json:"duration_ms"`
+	MaxBytes  int64  `json:"max_bytes,omitempty"`
+}
+
+// bandwidthReply 服务端的握手应答及数据流结束后的汇总
+type bandwidthReply struct {
+	Error       string `json:"error,omitempty"`
+	Bytes       int64  `json:"bytes"`
+	Retransmits int64  `json:"retransmits"`
+}
+
+type bandwidthDirectionResult struct {
+	Bytes       int64   `json:"bytes"`
+	Seconds     float64 `json:"seconds"`
+	Mbps        float64 `json:"mbps"`
+	Retransmits int64   `json:"retransmits"` // -1 表示当前平台无法获取
+}
+
+type bandwidthClientResult struct {
+	Mode     string                    `json:"mode"`
+	Target   string                    `json:"target"`
+	Address  string                    `json:"address"`
+	Streams  int                       `json:"streams"`
+	Duration float64                   `json:"duration"`
+	Upload   *bandwidthDirectionResult `json:"upload,omitempty"`
+	Download *bandwidthDirectionResult `json:"download,omitempty"`
+}
+
+type bandwidthServerResult struct {
+	Mode          string `json:"mode"`
+	Listen        string `json:"listen"`
+	Connections   int    `json:"connections"`
+	BytesReceived int64  `json:"bytes_received"`
+	BytesSent     int64  `json:"bytes_sent"`
+}
+
+// NewBandwidthTask 执行带宽测试任务，client 模式测试到目标的上下行吞吐，
+// server 模式在限定时间内作为测试服务端供其他 Agent 连接
+func NewBandwidthTask(taskID string, opts bandwidthOptions) {
+	if taskID == "" {
+		return
+	}
+	log.Printf("Executing bandwidth task %s in %s mode", taskID, opts.Mode)
+	var (
+		result interface{}
+		err    error
+	)
+	switch opts.Mode {
+	case "client":
+		result, err = runBandwidthClient(opts)
+	case "server":
+		result, err = runBandwidthServer(opts)
+	default:
+		err = fmt.Errorf("unsupported bandwidth mode %q", opts.Mode)
+	}
+	if err != nil {
+		uploadTaskResult(taskID, err.Error(), -1, time.Now())
+		return
+	}
+	body, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		uploadTaskResult(taskID, err.Error(), -1, time.Now())
+		return
+	}
+	uploadTaskResult(taskID, string(body), 0, time.Now())
+}
+
+func normalizeBandwidthOptions(opts *bandwidthOptions) error {
+	if opts.Key == "" {
+		return errors.New("bandwidth test requires a key")
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = bandwidthDefaultDuration
+	}
+	if opts.Duration > bandwidthMaxDuration {
+		opts.Duration = bandwidthMaxDuration
+	}
+	if opts.Streams <= 0 {
+		opts.Streams = bandwidthDefaultStreams
+	}
+	if opts.Streams > bandwidthMaxStreams {
+		opts.Streams = bandwidthMaxStreams
+	}
+	if opts.MaxBytes < 0 {
+		opts.MaxBytes = 0
+	}
+	switch opts.Direction {
+	case "":
+		opts.Direction = "both"
+	case "upload", "download", "both":
+	default:
+		return fmt.Errorf("unsupported bandwidth direction %q", opts.Direction)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = bandwidthDefaultTimeout
+	}
+	if opts.Timeout > bandwidthMaxTimeout {
+		opts.Timeout = bandwidthMaxTimeout
+	}
+	return nil
+}
+
+func runBandwidthClient(opts bandwidthOptions) (*bandwidthClientResult, error) {
+	if err := normalizeBandwidthOptions(&opts); err != nil {
+		return nil, err
+	}
+	host, port, err := net.SplitHostPort(opts.Target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target %q: %w", opts.Target, err)
+	}
+	ip, err := resolveIPWithOptions(strings.Trim(host, "[]"), opts.pingOptions)
+	if err != nil {
+		return nil, err
+	}
+	address := net.JoinHostPort(ip, port)
+	result := &bandwidthClientResult{
+		Mode:     "client",
+		Target:   opts.Target,
+		Address:  address,
+		Streams:  opts.Streams,
+		Duration: opts.Duration,
+	}
+	if opts.Direction == "upload" || opts.Direction == "both" {
+		if result.Upload, err = runBandwidthDirection(address, "upload", opts); err != nil {
+			return nil, fmt.Errorf("upload test failed: %w", err)
+		}
+	}
+	if opts.Direction == "download" || opts.Direction == "both" {
+		if result.Download, err = runBandwidthDirection(address, "download", opts); err != nil {
+			return nil, fmt.Errorf("download test failed: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// runBandwidthDirection 以 opts.Streams 条并行连接测试单个方向并汇总
+func runBandwidthDirection(address, direction string, opts bandwidthOptions) (*bandwidthDirectionResult, error) {
+	duration := time.Duration(opts.Duration * float64(time.Second))
+	perStreamBytes := int64(0)
+	if opts.MaxBytes > 0 {
+		perStreamBytes = (opts.MaxBytes + int64(opts.Streams) - 1) / int64(opts.Streams)
+	}
+
+	type streamResult struct {
+		bytes       int64
+		elapsed     time.Duration
+		retransmits int64
+		err         error
+	}
+	results := make([]streamResult, opts.Streams)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Streams; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := &results[i]
+			r.bytes, r.elapsed, r.retransmits, r.err = runBandwidthStream(address, bandwidthHello{
+				Key:       opts.Key,
+				Direction: direction,
+				Duration:  duration.Milliseconds(),
+				MaxBytes:  perStreamBytes,
+			}, opts.pingOptions)
+		}(i)
+	}
+	wg.Wait()
+
+	total := &bandwidthDirectionResult{}
+	var elapsed time.Duration
+	for _, r := range results {
+		if r.err != nil {
+			return nil, r.err
+		}
+		total.Bytes += r.bytes
+		if r.elapsed > elapsed {
+			elapsed = r.elapsed
+		}
+		if r.retransmits < 0 || total.Retransmits < 0 {
+			total.Retransmits = -1
+		} else {
+			total.Retransmits += r.retransmits
+		}
+	}
+	total.Seconds = elapsed.Seconds()
+	if total.Seconds > 0 {
+		total.Mbps = float64(total.Bytes) * 8 / total.Seconds / 1e6
+	}
+	return total, nil
+}
+
+func runBandwidthStream(address string, hello bandwidthHello, opts pingOptions) (int64, time.Duration, int64, error) {
+	host, _, _ := net.SplitHostPort(address)
+	dialer, err := newProbeDialer(10*time.Second, opts, host)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	conn, err := dialer.Dial("tcp", address)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer conn.Close()
+	duration := time.Duration(hello.Duration) * time.Millisecond
+	_ = conn.SetDeadline(time.Now().Add(duration + 30*time.Second))
+
+	reader := bufio.NewReader(conn)
+	if err := writeBandwidthMessage(conn, hello); err != nil {
+		return 0, 0, 0, err
+	}
+	var reply bandwidthReply
+	if err := readBandwidthMessage(reader, &reply); err != nil {
+		return 0, 0, 0, err
+	}
+	if reply.Error != "" {
+		return 0, 0, 0, errors.New(reply.Error)
+	}
+
+	start := time.Now()
+	if hello.Direction == "upload" {
+		if _, err := sendBandwidthStream(conn, start.Add(duration), hello.MaxBytes); err != nil {
+			return 0, 0, 0, err
+		}
+		if err := readBandwidthMessage(reader, &reply); err != nil {
+			return 0, 0, 0, err
+		}
+		retransmits, ok := tcpRetransmits(conn)
+		if !ok {
+			retransmits = -1
+		}
+		return reply.Bytes, time.Since(start), retransmits, nil
+	}
+
+	received, err := receiveBandwidthStream(reader)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	elapsed := time.Since(start)
+	if err := readBandwidthMessage(reader, &reply); err != nil {
+		return 0, 0, 0, err
+	}
+	return received, elapsed, reply.Retransmits, nil
+}
+
+// runBandwidthServer 对外开放端口属于远程控制能力，受 --disable-web-ssh 约束
+func runBandwidthServer(opts bandwidthOptions) (*bandwidthServerResult, error) {
+	if flags.DisableWebSsh {
+		return nil, errors.New("remote control is disabled, bandwidth server mode is not allowed")
+	}
+	if err := normalizeBandwidthOptions(&opts); err != nil {
+		return nil, err
+	}
+	if err := checkBandwidthListen(opts.Listen); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Bandwidth test server listening on %s for %ds", ln.Addr(), opts.Timeout)
+	return serveBandwidth(ln, opts.Key, time.Duration(opts.Timeout)*time.Second), nil
+}
+
+// checkBandwidthListen 要求监听地址显式给出具体 IP（不能是 0.0.0.0 或 ::），避免监听所有网卡
+func checkBandwidthListen(listen string) error {
+	if listen == "" {
+		return errors.New("bandwidth server requires a listen address")
+	}
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", listen, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("listen address %q must specify an IP", listen)
+	}
+	if ip.IsUnspecified() {
+		return fmt.Errorf("listen address %q must not be a wildcard address", listen)
+	}
+	return nil
+}
+
+// serveBandwidth 在 timeout 内接受测试连接，到期后关闭监听并等待进行中的测试结束
+func serveBandwidth(ln net.Listener, key string, timeout time.Duration) *bandwidthServerResult {
+	result := &bandwidthServerResult{Mode: "server", Listen: ln.Addr().String()}
+	timer := time.AfterFunc(timeout, func() { ln.Close() })
+	defer timer.Stop()
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, bandwidthMaxConns)
+	)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			break
+		}
+		select {
+		case sem <- struct{}{}:
+		default:
+			conn.Close()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			received, sent, err := handleBandwidthConn(conn, key)
+			if err != nil {
+				log.Printf("Bandwidth test connection from %s failed: %v", conn.RemoteAddr(), err)
+			}
+			mu.Lock()
+			result.Connections++
+			result.BytesReceived += received
+			result.BytesSent += sent
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+func handleBandwidthConn(conn net.Conn, key string) (int64, int64, error) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(15 * time.Second))
+	reader := bufio.NewReader(conn)
+	var hello bandwidthHello
+	if err := readBandwidthMessage(reader, &hello); err != nil {
+		return 0, 0, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hello.Key), []byte(key)) != 1 {
+		_ = writeBandwidthMessage(conn, bandwidthReply{Error: "invalid key"})
+		return 0, 0, errors.New("invalid key")
+	}
+	duration := time.Duration(hello.Duration) * time.Millisecond
+	if duration <= 0 || duration > time.Duration(bandwidthMaxDuration*float64(time.Second)) {
+		duration = time.Duration(bandwidthDefaultDuration * float64(time.Second))
+	}
+	_ = conn.SetDeadline(time.Now().Add(duration + 30*time.Second))
+	if err := writeBandwidthMessage(conn, bandwidthReply{}); err != nil {
+		return 0, 0, err
+	}
+
+	switch hello.Direction {
+	case "upload":
+		received, err := receiveBandwidthStream(reader)
+		if err != nil {
+			return received, 0, err
+		}
+		return received, 0, writeBandwidthMessage(conn, bandwidthReply{Bytes: received})
+	case "download":
+		sent, err := sendBandwidthStream(conn, time.Now().Add(duration), hello.MaxBytes)
+		if err != nil {
+			return 0, sent, err
+		}
+		retransmits, ok := tcpRetransmits(conn)
+		if !ok {
+			retransmits = -1
+		}
+		return 0, sent, writeBandwidthMessage(conn, bandwidthReply{Bytes: sent, Retransmits: retransmits})
+	default:
+		return 0, 0, fmt.Errorf("unsupported direction %q", hello.Direction)
+	}
+}
+
+// sendBandwidthStream 以长度前缀分块发送数据，直到超过截止时间或字节上限，最后发送长度为 0 的结束块
+func sendBandwidthStream(w io.Writer, deadline time.Time, maxBytes int64) (int64, error) {
+	buf := make([]byte, 4+bandwidthChunkSize)
+	var sent int64
+	for time.Now().Before(deadline) && (maxBytes <= 0 || sent < maxBytes) {
+		n := int64(bandwidthChunkSize)
+		if maxBytes > 0 && maxBytes-sent < n {
+			n = maxBytes - sent
+		}
+		binary.BigEndian.PutUint32(buf[:4], uint32(n))
+		if _, err := w.Write(buf[:4+n]); err != nil {
+			return sent, err
+		}
+		sent += n
+	}
+	binary.BigEndian.PutUint32(buf[:4], 0)
+	_, err := w.Write(buf[:4])
+	return sent, err
+}
+
+// receiveBandwidthStream 读取并丢弃分块数据，返回收到的有效字节数
+func receiveBandwidthStream(r io.Reader) (int64, error) {
+	var header [4]byte
+	var received int64
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return received, err
+		}
+		n := int64(binary.BigEndian.Uint32(header[:]))
+		if n == 0 {
+			return received, nil
+		}
+		copied, err := io.CopyN(io.Discard, r, n)
+		received += copied
+		if err != nil {
+			return received, err
+		}
+	}
+}
+
+func writeBandwidthMessage(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// readBandwidthMessage 读取一行 JSON 消息，长度受 bufio 缓冲区限制以防止恶意超长握手
+func readBandwidthMessage(r *bufio.Reader, v interface{}) error {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, v)
+}
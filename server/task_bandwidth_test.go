@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func startBandwidthTestServer(t *testing.T, key string) (string, <-chan *bandwidthServerResult) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	done := make(chan *bandwidthServerResult, 1)
+	go func() {
+		done <- serveBandwidth(ln, key, 3*time.Second)
+	}()
+	return ln.Addr().String(), done
+}
+
+func TestBandwidthClientAgainstLocalServer(t *testing.T) {
+	addr, done := startBandwidthTestServer(t, "secret")
+
+	result, err := runBandwidthClient(bandwidthOptions{
+		Mode:     "client",
+		Target:   addr,
+		Key:      "secret",
+		Duration: 0.2,
+		Streams:  2,
+		MaxBytes: 4 << 20,
+	})
+	if err != nil {
+		t.Fatalf("runBandwidthClient: %v", err)
+	}
+	for name, dir := range map[string]*bandwidthDirectionResult{"upload": result.Upload, "download": result.Download} {
+		if dir == nil {
+			t.Fatalf("missing %s result", name)
+		}
+		if dir.Bytes <= 0 || dir.Bytes > 4<<20 {
+			t.Fatalf("%s bytes %d outside (0, cap]", name, dir.Bytes)
+		}
+		if dir.Mbps <= 0 {
+			t.Fatalf("%s Mbps should be positive, got %f", name, dir.Mbps)
+		}
+	}
+
+	server := <-done
+	if server.Connections != 4 {
+		t.Fatalf("expected 4 connections served, got %d", server.Connections)
+	}
+	if server.BytesReceived != result.Upload.Bytes || server.BytesSent != result.Download.Bytes {
+		t.Fatalf("server counters %+v do not match client result %+v/%+v", server, result.Upload, result.Download)
+	}
+}
+
+func TestBandwidthServerRejectsWrongKey(t *testing.T) {
+	addr, _ := startBandwidthTestServer(t, "secret")
+
+	_, err := runBandwidthClient(bandwidthOptions{
+		Mode:      "client",
+		Target:    addr,
+		Key:       "wrong",
+		Direction: "upload",
+		Duration:  0.1,
+	})
+	if err == nil {
+		t.Fatal("expected wrong key to be rejected")
+	}
+}
+
+func TestBandwidthServerRequiresRemoteControlAndExplicitListen(t *testing.T) {
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+
+	flags.DisableWebSsh = true
+	if _, err := runBandwidthServer(bandwidthOptions{Mode: "server", Key: "secret", Listen: "127.0.0.1:0"}); err == nil {
+		t.Fatal("expected server mode to be refused when remote control is disabled")
+	}
+	flags.DisableWebSsh = false
+
+	for _, listen := range []string{"", ":5201", "localhost:5201", "0.0.0.0:5201", "[::]:5201"} {
+		if _, err := runBandwidthServer(bandwidthOptions{Mode: "server", Key: "secret", Listen: listen}); err == nil {
+			t.Fatalf("expected listen address %q to be rejected", listen)
+		}
+	}
+}
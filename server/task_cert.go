@@ -0,0 +1,228 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// certOptions 证书检查的可选参数
+type certOptions struct {
+	ServerName string `json:"server_name,omitempty"` // SNI，留空时使用目标主机名
+	CA         string `json:"ca,omitempty"`          // 自定义 CA，PEM 内容，允许远程控制时也可为文件路径；留空使用系统根证书
+}
+
+type certInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serial_number"`
+	SANs               []string  `json:"sans,omitempty"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	DaysRemaining      int       `json:"days_remaining"`
+	IsCA               bool      `json:"is_ca"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	FingerprintSHA256  string    `json:"fingerprint_sha256"`
+}
+
+type certInspection struct {
+	Target        string     `json:"target"`
+	ServerName    string     `json:"server_name"`
+	Address       string     `json:"address"`
+	TLSVersion    string     `json:"tls_version"`
+	CipherSuite   string     `json:"cipher_suite"`
+	Verified      bool       `json:"verified"`
+	VerifyError   string     `json:"verify_error,omitempty"`
+	DaysRemaining int        `json:"days_remaining"`
+	Expired       bool       `json:"expired"` // 叶子证书已过期，此时 DaysRemaining 为负数
+	Chain         []certInfo `json:"chain"`
+	CheckedAt     time.Time  `json:"checked_at"`
+}
+
+// NewCertTask 执行一次性证书检查，并以 JSON 形式上报任务结果。
+// 退出码：0 证书有效，1 证书校验失败，-1 无法建立 TLS 连接。
+func NewCertTask(taskID, target string, opts pingOptions) {
+	if taskID == "" {
+		return
+	}
+	if strings.TrimSpace(target) == "" {
+		uploadTaskResult(taskID, "No target provided", -1, time.Now())
+		return
+	}
+	log.Printf("Executing certificate check %s for %s", taskID, target)
+	result, err := inspectCertificate(target, opts, 10*time.Second)
+	if err != nil {
+		uploadTaskResult(taskID, err.Error(), -1, time.Now())
+		return
+	}
+	body, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		uploadTaskResult(taskID, err.Error(), -1, time.Now())
+		return
+	}
+	exitCode := 0
+	if !result.Verified {
+		exitCode = 1
+	}
+	uploadTaskResult(taskID, string(body), exitCode, time.Now())
+}
+
+// certPingValue 作为 ping 结果上报的剩余天数。负值会被面板视为失败或丢包，
+// 因此过期证书记为 0，过期情况与实际天数通过 detail 中的 expired、days_remaining 体现。
+func certPingValue(inspection *certInspection) int64 {
+	if inspection.DaysRemaining < 0 {
+		return 0
+	}
+	return int64(inspection.DaysRemaining)
+}
+
+// inspectCertificate 连接 host:port 并获取完整证书链，随后用系统根证书或自定义 CA 校验
+func inspectCertificate(target string, opts pingOptions, timeout time.Duration) (*certInspection, error) {
+	host, port := splitCertTarget(target)
+	if host == "" {
+		return nil, errors.New("invalid target")
+	}
+	serverName := opts.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+
+	roots, err := loadCertPool(opts.CA)
+	if err != nil {
+		return nil, err
+	}
+
+	ip, err := resolveIPWithOptions(host, opts)
+	if err != nil {
+		return nil, err
+	}
+	address := net.JoinHostPort(ip, port)
+	dialer, err := newProbeDialer(timeout, opts, ip)
+	if err != nil {
+		return nil, err
+	}
+	// 关闭内置校验以便在校验失败时依旧拿到证书链，校验在下方单独完成
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil, errors.New("no certificate presented by peer")
+	}
+
+	now := time.Now()
+	result := &certInspection{
+		Target:      target,
+		ServerName:  serverName,
+		Address:     address,
+		TLSVersion:  tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		CheckedAt:   now,
+	}
+	for _, cert := range state.PeerCertificates {
+		result.Chain = append(result.Chain, describeCertificate(cert, now))
+	}
+	result.DaysRemaining = result.Chain[0].DaysRemaining
+	result.Expired = now.After(state.PeerCertificates[0].NotAfter)
+
+	if err := verifyPeerCertificates(state.PeerCertificates, serverName, roots, now); err != nil {
+		result.VerifyError = err.Error()
+	} else {
+		result.Verified = true
+	}
+	return result, nil
+}
+
+func verifyPeerCertificates(certs []*x509.Certificate, serverName string, roots *x509.CertPool, now time.Time) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       strings.Trim(serverName, "[]"),
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	return err
+}
+
+func describeCertificate(cert *x509.Certificate, now time.Time) certInfo {
+	fingerprint := sha256.Sum256(cert.Raw)
+	var sans []string
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return certInfo{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       cert.SerialNumber.Text(16),
+		SANs:               sans,
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		DaysRemaining:      int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24)),
+		IsCA:               cert.IsCA,
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		FingerprintSHA256:  hex.EncodeToString(fingerprint[:]),
+	}
+}
+
+// splitCertTarget 解析检查目标，支持 host、host:port 与 https:// 前缀，默认端口 443
+func splitCertTarget(target string) (string, string) {
+	target = strings.TrimSpace(target)
+	target = strings.TrimPrefix(target, "https://")
+	if i := strings.IndexAny(target, "/?#"); i >= 0 {
+		target = target[:i]
+	}
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return strings.Trim(target, "[]"), "443"
+	}
+	return strings.Trim(host, "[]"), port
+}
+
+// loadCertPool 加载自定义 CA；为空时返回 nil 表示使用系统根证书。
+// 文件路径只在允许远程控制时接受，否则面板可借此探测本机文件是否存在
+func loadCertPool(ca string) (*x509.CertPool, error) {
+	ca = strings.TrimSpace(ca)
+	if ca == "" {
+		return nil, nil
+	}
+	pemData := []byte(ca)
+	if !strings.HasPrefix(ca, "-----BEGIN") {
+		if flags.DisableWebSsh {
+			return nil, errors.New("CA must be PEM content when remote control is disabled")
+		}
+		data, err := os.ReadFile(ca)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pemData = data
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, errors.New("no valid certificates found in CA")
+	}
+	return pool, nil
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInspectCertificateReportsChainAndVerifyError(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	target := strings.TrimPrefix(srv.URL, "https://")
+
+	result, err := inspectCertificate(target, pingOptions{}, 3*time.Second)
+	if err != nil {
+		t.Fatalf("inspectCertificate returned error: %v", err)
+	}
+	if len(result.Chain) == 0 {
+		t.Fatal("expected at least one certificate in chain")
+	}
+	if result.Verified || result.VerifyError == "" {
+		t.Fatalf("self-signed test certificate should fail system root verification, got %+v", result)
+	}
+	if result.DaysRemaining != result.Chain[0].DaysRemaining {
+		t.Fatalf("days remaining %d does not match leaf %d", result.DaysRemaining, result.Chain[0].DaysRemaining)
+	}
+	if len(result.Chain[0].SANs) == 0 {
+		t.Fatal("expected SANs on leaf certificate")
+	}
+}
+
+func TestInspectCertificateWithCustomCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	target := strings.TrimPrefix(srv.URL, "https://")
+	ca := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+
+	result, err := inspectCertificate(target, pingOptions{certOptions: certOptions{ServerName: "example.com", CA: ca}}, 3*time.Second)
+	if err != nil {
+		t.Fatalf("inspectCertificate returned error: %v", err)
+	}
+	if !result.Verified {
+		t.Fatalf("expected verification against custom CA to succeed, got %q", result.VerifyError)
+	}
+	if result.ServerName != "example.com" {
+		t.Fatalf("expected SNI example.com, got %q", result.ServerName)
+	}
+}
+
+func TestSplitCertTarget(t *testing.T) {
+	cases := []struct {
+		target, host, port string
+	}{
+		{"example.com", "example.com", "443"},
+		{"example.com:8443", "example.com", "8443"},
+		{"https://example.com/path", "example.com", "443"},
+		{"[2001:db8::1]:993", "2001:db8::1", "993"},
+		{"2001:db8::1", "2001:db8::1", "443"},
+	}
+	for _, tc := range cases {
+		host, port := splitCertTarget(tc.target)
+		if host != tc.host || port != tc.port {
+			t.Errorf("splitCertTarget(%q) = %q, %q; want %q, %q", tc.target, host, port, tc.host, tc.port)
+		}
+	}
+}
+
+func TestCertPingValueClampsExpired(t *testing.T) {
+	if got := certPingValue(&certInspection{DaysRemaining: 42}); got != 42 {
+		t.Fatalf("expected 42, got %d", got)
+	}
+	if got := certPingValue(&certInspection{DaysRemaining: -3, Expired: true}); got != 0 {
+		t.Fatalf("expired certificate should report 0, got %d", got)
+	}
+}
+
+func TestLoadCertPoolRejectsFilePathsWithoutRemoteControl(t *testing.T) {
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	flags.DisableWebSsh = true
+	if pool, err := loadCertPool(string(ca)); err != nil || pool == nil {
+		t.Fatalf("expected inline PEM to be accepted, got %v", err)
+	}
+	// 已存在与不存在的路径返回相同的错误，不泄露本机文件信息
+	existing, err := loadCertPool(path)
+	if existing != nil || err == nil {
+		t.Fatal("expected CA file path to be rejected when remote control is disabled")
+	}
+	if _, missing := loadCertPool(path + ".missing"); missing == nil || missing.Error() != err.Error() {
+		t.Fatalf("expected identical errors for existing and missing paths, got %v and %v", err, missing)
+	}
+
+	flags.DisableWebSsh = false
+	if pool, err := loadCertPool(path); err != nil || pool == nil {
+		t.Fatalf("expected CA file path to be accepted with remote control enabled, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/komari-monitor/komari-agent/terminal"
+)
+
+// NewTerminalSessionsTask 列出或关闭活动终端会话，结果以任务结果上报
+func NewTerminalSessionsTask(taskID, action, sessionID string) {
+	if taskID == "" {
+		return
+	}
+	switch action {
+	case "", "list":
+		body, err := json.MarshalIndent(terminal.ListSessions(), "", "  ")
+		if err != nil {
+			uploadTaskResult(taskID, err.Error(), -1, time.Now())
+			return
+		}
+		uploadTaskResult(taskID, string(body), 0, time.Now())
+	case "kill":
+		if err := terminal.KillSession(sessionID); err != nil {
+			uploadTaskResult(taskID, err.Error(), 1, time.Now())
+			return
+		}
+		uploadTaskResult(taskID, fmt.Sprintf("terminal session %s closed", sessionID), 0, time.Now())
+	default:
+		uploadTaskResult(taskID, fmt.Sprintf("unsupported action %q", action), -1, time.Now())
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"net"
 	"testing"
 	"time"
 )
@@ -61,3 +62,34 @@ func TestHTTPPing(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveIPWithOptionsHonoursFamily(t *testing.T) {
+	if ip, err := resolveIPWithOptions("127.0.0.1", pingOptions{IPVersion: 4}); err != nil || ip != "127.0.0.1" {
+		t.Fatalf("expected IPv4 literal to pass with ip_version 4, got %q, %v", ip, err)
+	}
+	if _, err := resolveIPWithOptions("127.0.0.1", pingOptions{IPVersion: 6}); err == nil {
+		t.Fatal("expected IPv4 literal to be rejected with ip_version 6")
+	}
+	if _, err := resolveIPWithOptions("::1", pingOptions{SourceIP: "192.0.2.1"}); err == nil {
+		t.Fatal("expected IPv6 target to be rejected for an IPv4 source address")
+	}
+	if _, err := resolveIPWithOptions("127.0.0.1", pingOptions{IPVersion: 5}); err == nil {
+		t.Fatal("expected invalid ip_version to be rejected")
+	}
+}
+
+func TestTCPProbeReportsAddress(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	result, err := tcpProbe(ln.Addr().String(), time.Second, pingOptions{SourceIP: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("tcpProbe: %v", err)
+	}
+	if result.Address != ln.Addr().String() {
+		t.Fatalf("expected address %s, got %s", ln.Addr().String(), result.Address)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package server
+
+import "net"
+
+// tcpRetransmits 当前平台不支持读取 TCP 重传统计
+func tcpRetransmits(conn net.Conn) (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/komari-monitor/komari-agent/utils"
+)
+
+const (
+	tunnelDialTimeout   = 10 * time.Second
+	tunnelWriteTimeout  = 10 * time.Second
+	tunnelCheckInterval = 30 * time.Second
+	tunnelBufferSize    = 32 << 10
+)
+
+var (
+	errTunnelDisabled   = errors.New("port forwarding is disabled")
+	errTunnelNotAllowed = errors.New("destination is not in the tunnel allowlist")
+	errTooManyTunnels   = errors.New("too many active tunnels")
+)
+
+// activeTunnels 当前活动的端口转发数量
+var activeTunnels tunnelCounter
+
+type tunnelCounter struct {
+	mu    sync.Mutex
+	count int
+}
+
+// acquire 占用一个转发名额，超过 --tunnel-max 时失败
+func (c *tunnelCounter) acquire() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if flags.TunnelMax > 0 && c.count >= flags.TunnelMax {
+		return fmt.Errorf("%w (limit %d)", errTooManyTunnels, flags.TunnelMax)
+	}
+	c.count++
+	return nil
+}
+
+func (c *tunnelCounter) release() {
+	c.mu.Lock()
+	c.count--
+	c.mu.Unlock()
+}
+
+// tunnelAllowed 检查目标是否匹配 --tunnel-allow 中的某一项，端口为 * 时匹配任意端口
+func tunnelAllowed(target string) error {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return fmt.Errorf("invalid tunnel target %q: %v", target, err)
+	}
+	allowed := false
+	for _, entry := range strings.Split(flags.TunnelAllow, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		allowed = true
+		allowHost, allowPort, err := net.SplitHostPort(entry)
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(allowHost, host) && (allowPort == "*" || allowPort == port) {
+			return nil
+		}
+	}
+	if !allowed {
+		return errTunnelDisabled
+	}
+	return fmt.Errorf("%w: %s", errTunnelNotAllowed, target)
+}
+
+// NewTunnel 处理 agent.tunnel.open：连接本机目标地址，并通过独立的 WebSocket 与面板桥接
+func NewTunnel(requestID, target string) {
+	if requestID == "" {
+		return
+	}
+	target = strings.TrimSpace(target)
+
+	var tcpConn net.Conn
+	err := func() error {
+		if flags.DisableWebSsh {
+			return errors.New("remote control is disabled")
+		}
+		if err := tunnelAllowed(target); err != nil {
+			return err
+		}
+		if err := activeTunnels.acquire(); err != nil {
+			return err
+		}
+		conn, err := net.DialTimeout("tcp", target, tunnelDialTimeout)
+		if err != nil {
+			activeTunnels.release()
+			return err
+		}
+		tcpConn = conn
+		return nil
+	}()
+
+	wsConn, dialErr := dialTunnelWebSocket(requestID)
+	if dialErr != nil {
+		log.Printf("Failed to establish tunnel connection: %v", dialErr)
+		if tcpConn != nil {
+			tcpConn.Close()
+			activeTunnels.release()
+		}
+		return
+	}
+	defer wsConn.Close()
+
+	if err != nil {
+		log.Printf("Tunnel %s to %s rejected: %v", requestID, target, err)
+		closeTunnelWebSocket(wsConn, websocket.ClosePolicyViolation, err.Error())
+		return
+	}
+	defer activeTunnels.release()
+
+	log.Printf("Tunnel %s opened to %s", requestID, target)
+	reason := bridgeTunnel(wsConn, tcpConn, time.Duration(flags.TunnelIdleTimeout)*time.Minute)
+	log.Printf("Tunnel %s to %s closed: %s", requestID, target, reason)
+	closeTunnelWebSocket(wsConn, websocket.CloseNormalClosure, reason)
+}
+
+// rejectTunnel 建立端口转发连接后立即以 reason 关闭，告知面板请求未被执行
+func rejectTunnel(requestID, reason string) {
+	if requestID == "" {
+		return
+	}
+	wsConn, err := dialTunnelWebSocket(requestID)
+	if err != nil {
+		log.Printf("Failed to establish tunnel connection: %v", err)
+		return
+	}
+	defer wsConn.Close()
+	log.Printf("Tunnel %s rejected: %s", requestID, reason)
+	closeTunnelWebSocket(wsConn, websocket.ClosePolicyViolation, reason)
+}
+
+// dialTunnelWebSocket 建立端口转发专用的 WebSocket 连接
+func dialTunnelWebSocket(requestID string) (*websocket.Conn, error) {
+	endpoint := strings.TrimSuffix(flags.Endpoint, "/") + "/api/clients/tunnel?token=" + flags.Token + "&id=" + requestID
+	endpoint = "ws" + strings.TrimPrefix(endpoint, "http")
+	if convertedEndpoint, err := utils.ConvertIDNToASCII(endpoint); err == nil {
+		endpoint = convertedEndpoint
+	} else {
+		log.Printf("Warning: Failed to convert Tunnel WebSocket IDN to ASCII: %v", err)
+	}
+	conn, _, err := newWSDialer().Dial(endpoint, nil)
+	return conn, err
+}
+
+func closeTunnelWebSocket(conn *websocket.Conn, code int, reason string) {
+	_ = conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(time.Second))
+}
+
+// bridgeTunnel 在 WebSocket 与 TCP 连接之间双向转发数据，直到任一端关闭或空闲超时，返回关闭原因
+func bridgeTunnel(wsConn *websocket.Conn, tcpConn net.Conn, idleTimeout time.Duration) string {
+	var lastActivity atomic.Int64
+	touch := func() { lastActivity.Store(time.Now().UnixNano()) }
+	touch()
+
+	done := make(chan string, 2)
+	// WebSocket -> TCP
+	go func() {
+		for {
+			_, p, err := wsConn.ReadMessage()
+			if err != nil {
+				done <- "dashboard closed the connection"
+				return
+			}
+			touch()
+			if _, err := tcpConn.Write(p); err != nil {
+				done <- "destination write failed"
+				return
+			}
+		}
+	}()
+	// TCP -> WebSocket
+	go func() {
+		buf := make([]byte, tunnelBufferSize)
+		for {
+			n, err := tcpConn.Read(buf)
+			if n > 0 {
+				touch()
+				wsConn.SetWriteDeadline(time.Now().Add(tunnelWriteTimeout))
+				if werr := wsConn.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+					done <- "dashboard write failed"
+					return
+				}
+			}
+			if err != nil {
+				done <- "destination closed the connection"
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(tunnelCheckInterval)
+	defer ticker.Stop()
+	defer tcpConn.Close()
+	for {
+		select {
+		case reason := <-done:
+			return reason
+		case now := <-ticker.C:
+			if idleTimeout > 0 && now.Sub(time.Unix(0, lastActivity.Load())) > idleTimeout {
+				return "idle timeout"
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestTunnelAllowed(t *testing.T) {
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+
+	flags.TunnelAllow = ""
+	if err := tunnelAllowed("127.0.0.1:5432"); !errors.Is(err, errTunnelDisabled) {
+		t.Fatalf("expected tunnels to be disabled, got %v", err)
+	}
+
+	flags.TunnelAllow = "127.0.0.1:5432, localhost:*, [::1]:8080"
+	for target, want := range map[string]bool{
+		"127.0.0.1:5432": true,
+		"127.0.0.1:5433": false,
+		"LOCALHOST:3000": true,
+		"[::1]:8080":     true,
+		"[::1]:8081":     false,
+		"10.0.0.1:5432":  false,
+	} {
+		if err := tunnelAllowed(target); (err == nil) != want {
+			t.Errorf("tunnelAllowed(%q) = %v, want allowed=%v", target, err, want)
+		}
+	}
+	if err := tunnelAllowed("no-port"); err == nil {
+		t.Fatal("expected invalid target to be rejected")
+	}
+}
+
+func TestTunnelCounterLimit(t *testing.T) {
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+	flags.TunnelMax = 1
+
+	var c tunnelCounter
+	if err := c.acquire(); err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	if err := c.acquire(); !errors.Is(err, errTooManyTunnels) {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+	c.release()
+	if err := c.acquire(); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+}
+
+func TestBridgeTunnelForwardsBothWays(t *testing.T) {
+	// 本地回显服务作为转发目标
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+
+	// 模拟面板侧的 WebSocket
+	dashboard := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		dashboard <- conn
+	}))
+	defer srv.Close()
+	wsConn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wsConn.Close()
+	peer := <-dashboard
+	defer peer.Close()
+
+	tcpConn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(chan string, 1)
+	go func() { result <- bridgeTunnel(wsConn, tcpConn, time.Minute) }()
+
+	if err := peer.WriteMessage(websocket.BinaryMessage, []byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, p, err := peer.ReadMessage()
+	if err != nil {
+		t.Fatalf("read echo: %v", err)
+	}
+	if string(p) != "ping" {
+		t.Fatalf("unexpected echo %q", p)
+	}
+
+	peer.Close()
+	select {
+	case reason := <-result:
+		if reason == "" {
+			t.Fatal("expected a close reason")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("bridge did not stop after dashboard closed")
+	}
+}
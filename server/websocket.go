@@ -49,6 +49,18 @@ func EstablishWebSocketConnection() {
 	nextProtocol := requestedProtocolVersion()
 	activeProtocol := 0
 	var readDone <-chan struct{}
+	connected := false
+	// serve 开始处理新连接上的指令。重连时在连接登记后重新上报基础信息，首次连接时启动流程已经上报过
+	serve := func() {
+		done := make(chan struct{})
+		readDone = done
+		var onReady func()
+		if connected {
+			onReady = resendBasicInfo
+		}
+		connected = true
+		go handleWebSocketMessages(conn, activeProtocol, done, onReady)
+	}
 
 	for {
 		select {
@@ -68,9 +80,7 @@ func EstablishWebSocketConnection() {
 						nextProtocol = connectProtocol
 						setConnectionProtocolVersion(activeProtocol)
 						log.Printf("WebSocket connected using v%d protocol", activeProtocol)
-						done := make(chan struct{})
-						readDone = done
-						go handleWebSocketMessages(conn, activeProtocol, done)
+						serve()
 						break
 					} else if shouldFallbackToV1(connectProtocol, err) {
 						log.Printf("v2 WebSocket endpoint failed (%v), falling back to v1 until this connection is lost", err)
@@ -104,9 +114,7 @@ func EstablishWebSocketConnection() {
 					activeProtocol = connectProtocol
 					nextProtocol = connectProtocol
 					setConnectionProtocolVersion(activeProtocol)
-					done := make(chan struct{})
-					readDone = done
-					go handleWebSocketMessages(conn, activeProtocol, done)
+					serve()
 				}
 			}
 
@@ -223,7 +231,7 @@ func runV2PullLoop(ctx context.Context, errCh chan<- error) {
 		pullID := fmt.Sprintf("pull-%d", time.Now().UnixNano())
 		ackIDs := snapshotV2AckEventIDs()
 		payload := v2.NewRequest(pullID, v2.MethodAgentPull, map[string]interface{}{
-			"capabilities":  []string{"exec", "ping", "message", "event", "terminal"},
+			"capabilities":  []string{"exec", "ping", "cert", "bandwidth", "message", "event", "terminal", "terminal.sessions", "tunnel"},
 			"ack_event_ids": ackIDs,
 		})
 		resp, err := postV2RequestContext(ctx, payload)
@@ -299,6 +307,9 @@ func processV2ResponseEvents(resp *v2.Response) {
 		log.Println("Failed to bind v2 event result:", err)
 		return
 	}
+	if len(result.Capabilities) > 0 {
+		setServerCapabilities(result.Capabilities)
+	}
 	for _, event := range result.Events {
 		if processV2Event(nil, event.Method, event.Params, event.ID) {
 			addV2AckEventID(event.ID)
@@ -353,6 +364,16 @@ func markV2EventSeen(id string) bool {
 	return true
 }
 
+// forgetV2Event 撤销事件的处理登记，使重新下发的同一事件能够再次执行
+func forgetV2Event(id string) {
+	if id == "" {
+		return
+	}
+	v2AckMu.Lock()
+	defer v2AckMu.Unlock()
+	delete(v2SeenEvents, id)
+}
+
 func connectWebSocket(websocketEndpoint string) (*ws.SafeConn, error) {
 	dialer := newWSDialer()
 
@@ -363,12 +384,24 @@ func connectWebSocket(websocketEndpoint string) (*ws.SafeConn, error) {
 		}
 		return nil, err
 	}
+	if resp != nil {
+		setServerCapabilities(strings.Split(resp.Header.Get(serverCapabilitiesHeader), ","))
+	}
 
 	return ws.NewSafeConn(conn), nil
 }
 
-func handleWebSocketMessages(conn *ws.SafeConn, protocolVersion int, done chan<- struct{}) {
+// handleWebSocketMessages 处理连接上的服务端指令，直到连接断开；onReady 在连接登记为活动连接后调用，可为 nil
+func handleWebSocketMessages(conn *ws.SafeConn, protocolVersion int, done chan<- struct{}, onReady func()) {
 	defer close(done)
+	if protocolVersion >= 2 {
+		setActiveV2Conn(conn)
+		defer clearActiveV2Conn(conn)
+	}
+	if onReady != nil {
+		// 上报可能经由本连接发起请求并等待响应，不能阻塞读取
+		go onReady()
+	}
 	for {
 		_, message_raw, err := conn.ReadMessage()
 		if err != nil {
@@ -379,16 +412,7 @@ func handleWebSocketMessages(conn *ws.SafeConn, protocolVersion int, done chan<-
 			JSONRPC string      `json:"jsonrpc,omitempty"`
 			Method  string      `json:"method,omitempty"`
 			Params  interface{} `json:"params,omitempty"`
-			Message string      `json:"message"`
-			// Terminal
-			TerminalId string `json:"request_id,omitempty"`
-			// Remote Exec
-			ExecCommand string `json:"command,omitempty"`
-			ExecTaskID  string `json:"task_id,omitempty"`
-			// Ping
-			PingTaskID uint   `json:"ping_task_id,omitempty"`
-			PingType   string `json:"ping_type,omitempty"`
-			PingTarget string `json:"ping_target,omitempty"`
+			ID      interface{} `json:"id,omitempty"`
 		}
 		err = json.Unmarshal(message_raw, &message)
 		if err != nil {
@@ -396,77 +420,81 @@ func handleWebSocketMessages(conn *ws.SafeConn, protocolVersion int, done chan<-
 			continue
 		}
 		if message.JSONRPC == v2.Version && protocolVersion >= 2 {
-			processV2Event(conn, message.Method, message.Params, "")
+			if message.Method == "" {
+				// 服务端对 agent 请求的响应
+				var resp v2.Response
+				if err := json.Unmarshal(message_raw, &resp); err == nil && !resolveV2Call(&resp) {
+					log.Printf("unexpected v2 response id %v", resp.ID)
+				}
+				continue
+			}
+			rpcErr := defaultRouter.dispatch(&rpcContext{Conn: conn, Protocol: 2, Method: message.Method, Redeliverable: message.ID != nil}, message.Params)
+			if message.ID != nil {
+				// 带 ID 的请求需要回复处理结果
+				resp := v2.Response{JSONRPC: v2.Version, ID: message.ID, Error: rpcErr}
+				if rpcErr == nil {
+					resp.Result = map[string]string{"status": "accepted"}
+				}
+				conn.WriteJSON(resp)
+			}
 			continue
 		}
 
-		if message.Message == "terminal" || message.TerminalId != "" {
-			go establishTerminalConnection(flags.Token, message.TerminalId, flags.Endpoint)
-			continue
-		}
-		if message.Message == "exec" {
-			go NewTask(message.ExecTaskID, message.ExecCommand)
+		// v1 消息转换为对应的 v2 方法后统一分发
+		v1msg, err := decodeV1Message(message_raw)
+		if err != nil {
+			log.Println("Bad ws message:", err)
 			continue
 		}
-		if message.Message == "ping" || message.PingTaskID != 0 || message.PingType != "" || message.PingTarget != "" {
-			go NewPingTask(conn, protocolVersion, message.PingTaskID, message.PingType, message.PingTarget)
-			continue
+		if method, params := translateV1(v1msg); method != "" {
+			defaultRouter.dispatch(&rpcContext{Conn: conn, Protocol: protocolVersion, Method: method}, params)
 		}
 	}
 }
 
+// processV2Event 分发通过 POST 拉取或上报响应得到的 v2 事件，返回是否应确认该事件。
+// 未知方法、参数错误与限流不确认，由服务端稍后重新下发；其余（包括被拒绝的）均确认，避免服务端重复下发
 func processV2Event(conn *ws.SafeConn, method string, params interface{}, eventID string) bool {
-	if !markV2EventSeen(eventID) {
-		return true
+	return processEvent(defaultRouter, &rpcContext{Conn: conn, Protocol: 2, Method: method, EventID: eventID, Redeliverable: true}, params)
+}
+
+func processEvent(r *rpcRouter, ctx *rpcContext, params interface{}) bool {
+	return !retryableRPCError(r.dispatch(ctx, params))
+}
+
+// connectWebSocket attempts to establish a WebSocket connection and upload basic info
+
+// establishTerminalConnection 建立终端连接并使用terminal包处理终端操作，
+// opts 携带会话 ID、用户、shell 等终端参数
+func establishTerminalConnection(token, endpoint string, opts terminal.Options) {
+	conn, err := dialTerminalWebSocket(token, endpoint, opts.RequestID)
+	if err != nil {
+		log.Println("Failed to establish terminal connection:", err)
+		return
 	}
-	switch method {
-	case v2.MethodAgentExec:
-		var p struct {
-			TaskID  string `json:"task_id"`
-			Command string `json:"command"`
-		}
-		if err := v2.BindParams(params, &p); err == nil {
-			go NewTask(p.TaskID, p.Command)
-			return true
-		} else {
-			log.Printf("bad v2 exec params: %v", err)
-		}
-	case v2.MethodAgentPing:
-		var p struct {
-			TaskID uint   `json:"ping_task_id"`
-			Type   string `json:"ping_type"`
-			Target string `json:"ping_target"`
-		}
-		if err := v2.BindParams(params, &p); err == nil {
-			go NewPingTask(conn, 2, p.TaskID, p.Type, p.Target)
-			return true
-		} else {
-			log.Printf("bad v2 ping params: %v", err)
-		}
-	case v2.MethodAgentTerminal:
-		var p struct {
-			RequestID string `json:"request_id"`
-		}
-		if err := v2.BindParams(params, &p); err == nil {
-			go establishTerminalConnection(flags.Token, p.RequestID, flags.Endpoint)
-			return true
-		} else {
-			log.Printf("bad v2 terminal params: %v", err)
-		}
-	case v2.MethodAgentMessage, v2.MethodAgentEvent:
-		log.Printf("received v2 %s: %+v", method, params)
-		return true
-	default:
-		log.Printf("unknown v2 event method %s", method)
+
+	// 启动终端
+	terminal.StartTerminal(conn, opts)
+	if conn != nil {
+		conn.Close()
 	}
-	return false
 }
 
-// connectWebSocket attempts to establish a WebSocket connection and upload basic info
+// rejectTerminalConnection 建立终端连接后写入原因并关闭，告知面板请求未被执行
+func rejectTerminalConnection(token, endpoint, requestID, reason string) {
+	conn, err := dialTerminalWebSocket(token, endpoint, requestID)
+	if err != nil {
+		log.Println("Failed to establish terminal connection:", err)
+		return
+	}
+	defer conn.Close()
+	log.Printf("Terminal request %s rejected: %s", requestID, reason)
+	conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("Error: %s\r\n", reason)))
+}
 
-// establishTerminalConnection 建立终端连接并使用terminal包处理终端操作
-func establishTerminalConnection(token, id, endpoint string) {
-	endpoint = strings.TrimSuffix(endpoint, "/") + "/api/clients/terminal?token=" + token + "&id=" + id
+// dialTerminalWebSocket 建立终端专用的 WebSocket 连接
+func dialTerminalWebSocket(token, endpoint, requestID string) (*websocket.Conn, error) {
+	endpoint = strings.TrimSuffix(endpoint, "/") + "/api/clients/terminal?token=" + token + "&id=" + requestID
 	endpoint = "ws" + strings.TrimPrefix(endpoint, "http")
 
 	// 转换中文域名为 ASCII 兼容编码
@@ -477,19 +505,8 @@ func establishTerminalConnection(token, id, endpoint string) {
 	}
 
 	// 使用与主 WS 相同的拨号策略
-	dialer := newWSDialer()
-
-	conn, _, err := dialer.Dial(endpoint, nil)
-	if err != nil {
-		log.Println("Failed to establish terminal connection:", err)
-		return
-	}
-
-	// 启动终端
-	terminal.StartTerminal(conn)
-	if conn != nil {
-		conn.Close()
-	}
+	conn, _, err := newWSDialer().Dial(endpoint, nil)
+	return conn, err
 }
 
 // newWSDialer 构造统一的 WebSocket 拨号器（自定义解析、IPv4/IPv6 动态排序、可选 TLS 忽略）
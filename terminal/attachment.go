@@ -0,0 +1,253 @@
+package terminal
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 输出合并与流控参数
+const (
+	outputBatchWindow  = 10 * time.Millisecond // 合并输出的时间窗口
+	outputMaxFrame     = 32 << 10              // 单个 WebSocket 帧的最大字节数
+	outputHighWater    = 256 << 10             // 待发送数据超过此值时暂停读取终端
+	outputWriteTimeout = 10 * time.Second      // 单帧写入超时，超时视为连接断开
+	viewerMaxPending   = 4 * outputHighWater   // 只读观看者积压超过此值时断开，避免拖慢会话
+)
+
+// attachment 接入会话的一条 WebSocket 连接。
+// 终端输出先进入待发送队列，由独立的写协程按时间窗口合并成较大的帧发出。
+// 操作者的队列积压超过上限或客户端请求暂停时，enqueue 阻塞，从而暂停读取终端；
+// 观看者不阻塞会话，暂停期间的输出在恢复后从会话缓冲区补齐。
+// 控制通知（如角色变化）以文本帧发送，优先于终端输出。
+type attachment struct {
+	id   string // 会话内的参与者 ID
+	conn *websocket.Conn
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	pending    []byte
+	pendingEnd int64 // pending 末尾在会话输出流中的偏移
+	delivered  int64 // 已成功写入连接的输出流偏移，断开后从此处回放
+	behind     bool  // 有输出未入队，需要从会话缓冲区补齐
+	resync     func(a *attachment)
+	notices    [][]byte
+	viewer     bool // 只读观看者，不对终端施加流控
+	paused     bool // 客户端通过 pause 消息请求暂停输出
+	closed     bool
+}
+
+func newAttachment(id string, conn *websocket.Conn) *attachment {
+	a := &attachment{id: id, conn: conn}
+	a.cond = sync.NewCond(&a.mu)
+	go a.writeLoop()
+	return a
+}
+
+// prime 直接放入待发送数据（如回放内容），不受积压上限约束。
+// end 为回放内容末尾在输出流中的偏移，resync 用于落后时从会话缓冲区补齐输出
+func (a *attachment) prime(p []byte, end int64, resync func(a *attachment)) {
+	a.mu.Lock()
+	a.pending = append(a.pending, p...)
+	a.pendingEnd, a.delivered = end, end-int64(len(a.pending))
+	a.resync = resync
+	a.behind = false
+	a.cond.Broadcast()
+	a.mu.Unlock()
+}
+
+// enqueue 追加操作者的终端输出，end 为 p 末尾在输出流中的偏移。
+// 连接拥塞或被暂停时阻塞，直到恢复发送、降为观看者或连接关闭
+func (a *attachment) enqueue(p []byte, end int64) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for !a.closed && !a.viewer && (a.paused || a.needsResync() || len(a.pending) >= outputHighWater) {
+		a.cond.Wait()
+	}
+	if a.closed {
+		return
+	}
+	a.appendAt(p, end)
+}
+
+// offer 以不阻塞的方式追加输出，供只读观看者使用。需持有会话锁。
+// 暂停期间只标记为落后，恢复后补齐；未暂停但积压过多时断开该连接
+func (a *attachment) offer(p []byte, end int64) {
+	a.mu.Lock()
+	if a.closed || a.behind {
+		a.mu.Unlock()
+		return
+	}
+	if a.paused {
+		a.behind = true
+		a.mu.Unlock()
+		return
+	}
+	if len(a.pending)+len(p) > viewerMaxPending {
+		// 关闭帧的写入放到单独的协程，之后的输出直接丢弃
+		a.behind = true
+		a.mu.Unlock()
+		go a.closeWithReason("viewer too slow")
+		return
+	}
+	a.appendAt(p, end)
+	a.mu.Unlock()
+}
+
+// appendAt 按输出流偏移追加输出，跳过已经入队（如补齐时已取得）的部分，需持有 a.mu
+func (a *attachment) appendAt(p []byte, end int64) {
+	if a.behind || end <= a.pendingEnd {
+		return
+	}
+	if start := end - int64(len(p)); start < a.pendingEnd {
+		p = p[a.pendingEnd-start:]
+	}
+	a.pending = append(a.pending, p...)
+	a.pendingEnd = end
+	a.cond.Broadcast()
+}
+
+// setViewer 在角色变化时调用，降为观看者会释放阻塞在 enqueue 中的会话
+func (a *attachment) setViewer(viewer bool) {
+	a.mu.Lock()
+	a.viewer = viewer
+	a.cond.Broadcast()
+	a.mu.Unlock()
+}
+
+// catchUp 从会话缓冲区补齐落后的输出，需持有会话锁，保证与 offer 的输出顺序一致
+func (a *attachment) catchUp(output *scrollback) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.needsResync() {
+		return
+	}
+	a.pending = append(a.pending, output.Since(a.pendingEnd)...)
+	a.pendingEnd = output.Offset()
+	a.behind = false
+	a.cond.Broadcast()
+}
+
+// needsResync 落后且连接已可以继续发送，需持有 a.mu
+func (a *attachment) needsResync() bool {
+	return a.behind && a.resync != nil && !a.closed && !a.paused && len(a.pending) < outputHighWater
+}
+
+// deliveredOffset 返回已成功发送到连接的输出流偏移
+func (a *attachment) deliveredOffset() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.delivered
+}
+
+// notify 发送 JSON 格式的控制通知
+func (a *attachment) notify(v interface{}) {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	if !a.closed {
+		a.notices = append(a.notices, msg)
+		a.cond.Broadcast()
+	}
+	a.mu.Unlock()
+}
+
+// setPaused 响应客户端的 pause/resume 消息
+func (a *attachment) setPaused(paused bool) {
+	a.mu.Lock()
+	a.paused = paused
+	a.cond.Broadcast()
+	a.mu.Unlock()
+}
+
+// writeLoop 将待发送数据合并成帧写入连接
+func (a *attachment) writeLoop() {
+	for {
+		a.mu.Lock()
+		for !a.closed && len(a.notices) == 0 && !a.needsResync() && (a.paused || len(a.pending) == 0) {
+			a.cond.Wait()
+		}
+		if a.closed {
+			a.mu.Unlock()
+			return
+		}
+		if len(a.notices) > 0 {
+			notice := a.notices[0]
+			a.notices = a.notices[1:]
+			a.mu.Unlock()
+			if !a.writeFrame(websocket.TextMessage, notice) {
+				return
+			}
+			continue
+		}
+		if a.needsResync() {
+			// 补齐需要先获取会话锁，释放 a.mu 以保持会话锁在前的加锁顺序
+			resync := a.resync
+			a.mu.Unlock()
+			resync(a)
+			continue
+		}
+		if len(a.pending) < outputMaxFrame {
+			// 等待一个时间窗口，让连续的小块输出合并到同一帧
+			a.mu.Unlock()
+			time.Sleep(outputBatchWindow)
+			a.mu.Lock()
+		}
+		n := min(len(a.pending), outputMaxFrame)
+		frame := make([]byte, n)
+		copy(frame, a.pending)
+		a.pending = a.pending[:copy(a.pending, a.pending[n:])]
+		end := a.pendingEnd - int64(len(a.pending))
+		a.cond.Broadcast()
+		a.mu.Unlock()
+
+		if !a.writeFrame(websocket.BinaryMessage, frame) {
+			return
+		}
+		a.mu.Lock()
+		a.delivered = end
+		a.mu.Unlock()
+	}
+}
+
+// writeFrame 带超时地写入一帧，失败时关闭连接
+func (a *attachment) writeFrame(messageType int, data []byte) bool {
+	a.conn.SetWriteDeadline(time.Now().Add(outputWriteTimeout))
+	if err := a.conn.WriteMessage(messageType, data); err != nil {
+		a.close()
+		return false
+	}
+	return true
+}
+
+func (a *attachment) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return
+	}
+	a.closed = true
+	a.pending = nil
+	a.notices = nil
+	a.cond.Broadcast()
+	if a.conn != nil {
+		a.conn.Close()
+	}
+}
+
+// closeWithReason 发送带原因的关闭帧后关闭连接；WriteControl 可与写协程的写操作并发调用
+func (a *attachment) closeWithReason(reason string) {
+	if a.conn != nil {
+		_ = a.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason),
+			time.Now().Add(time.Second))
+	}
+	a.close()
+}
@@ -0,0 +1,154 @@
+package terminal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestAttachment 返回一个写入到测试 WebSocket 的 attachment 以及对端连接
+func newTestAttachment(t *testing.T) (*attachment, *websocket.Conn) {
+	t.Helper()
+	serverConn := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		serverConn <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	att := newAttachment("", <-serverConn)
+	t.Cleanup(att.close)
+	return att, client
+}
+
+func TestAttachmentCoalescesOutput(t *testing.T) {
+	att, client := newTestAttachment(t)
+	for i := 0; i < 100; i++ {
+		att.enqueue([]byte("x"), int64(i+1))
+	}
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var received, frames int
+	for received < 100 {
+		_, p, err := client.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		received += len(p)
+		frames++
+	}
+	if frames >= 100 {
+		t.Fatalf("expected small writes to be coalesced, got %d frames", frames)
+	}
+}
+
+func TestAttachmentPauseBlocksOutput(t *testing.T) {
+	att, client := newTestAttachment(t)
+	att.setPaused(true)
+
+	enqueued := make(chan struct{})
+	go func() {
+		att.enqueue([]byte("hello"), 5)
+		close(enqueued)
+	}()
+	select {
+	case <-enqueued:
+		t.Fatal("enqueue should block while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	att.setPaused(false)
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, p, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("read after resume: %v", err)
+	}
+	if string(p) != "hello" {
+		t.Fatalf("unexpected output %q", p)
+	}
+}
+
+func TestAttachmentCloseReleasesBlockedWriter(t *testing.T) {
+	att, _ := newTestAttachment(t)
+	att.setPaused(true)
+
+	done := make(chan struct{})
+	go func() {
+		att.enqueue([]byte("data"), 4)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	att.close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("enqueue still blocked after close")
+	}
+}
+
+func TestAttachmentPausedViewerCatchesUpFromScrollback(t *testing.T) {
+	att, client := newTestAttachment(t)
+	output := newScrollback(1024)
+	var mu sync.Mutex
+	resync := func(a *attachment) {
+		mu.Lock()
+		defer mu.Unlock()
+		a.catchUp(output)
+	}
+	write := func(p string) {
+		mu.Lock()
+		defer mu.Unlock()
+		output.Write([]byte(p))
+		att.offer([]byte(p), output.Offset())
+	}
+	att.prime(nil, 0, resync)
+	att.setViewer(true)
+	att.setPaused(true)
+
+	// 观看者暂停期间 offer 不阻塞，输出只进入会话缓冲区
+	write("hello")
+	write(" world")
+
+	att.setPaused(false)
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var got string
+	for got != "hello world" {
+		_, p, err := client.ReadMessage()
+		if err != nil {
+			t.Fatalf("read after resume: %v (got %q)", err, got)
+		}
+		got += string(p)
+	}
+	deadline := time.Now().Add(time.Second)
+	for att.deliveredOffset() != int64(len(got)) {
+		if time.Now().After(deadline) {
+			t.Fatalf("delivered offset %d, expected %d", att.deliveredOffset(), len(got))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAttachmentDeliveredOffsetExcludesQueuedOutput(t *testing.T) {
+	att := &attachment{}
+	att.cond = sync.NewCond(&att.mu)
+	att.prime([]byte("replay"), 10, nil)
+	att.enqueue([]byte("more"), 14)
+	if got := att.deliveredOffset(); got != 4 {
+		t.Fatalf("expected only output before the replay to count as delivered, got %d", got)
+	}
+}
@@ -0,0 +1,84 @@
+//go:build !windows
+
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// passwdPath 用户数据库路径，测试时可替换
+var passwdPath = "/etc/passwd"
+
+// passwdEntry /etc/passwd 中的一条用户记录
+type passwdEntry struct {
+	Name  string
+	UID   int
+	GID   int
+	Home  string
+	Shell string
+}
+
+// parsePasswdLine 解析 name:password:uid:gid:gecos:home:shell 格式的一行
+func parsePasswdLine(line string) (passwdEntry, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return passwdEntry{}, false
+	}
+	parts := strings.Split(line, ":")
+	if len(parts) != 7 || parts[0] == "" {
+		return passwdEntry{}, false
+	}
+	uid, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return passwdEntry{}, false
+	}
+	gid, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return passwdEntry{}, false
+	}
+	return passwdEntry{Name: parts[0], UID: uid, GID: gid, Home: parts[5], Shell: parts[6]}, true
+}
+
+// lookupPasswd 返回第一条满足条件的用户记录
+func lookupPasswd(match func(passwdEntry) bool) (*passwdEntry, bool, error) {
+	f, err := os.Open(passwdPath)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if entry, ok := parsePasswdLine(scanner.Text()); ok && match(entry) {
+			return &entry, true, nil
+		}
+	}
+	return nil, false, scanner.Err()
+}
+
+// lookupUserByUID 按 UID 查找用户
+func lookupUserByUID(uid int) (*passwdEntry, error) {
+	entry, ok, err := lookupPasswd(func(e passwdEntry) bool { return e.UID == uid })
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no passwd entry for uid %d", uid)
+	}
+	return entry, nil
+}
+
+// lookupUserByName 按用户名查找用户
+func lookupUserByName(name string) (*passwdEntry, error) {
+	entry, ok, err := lookupPasswd(func(e passwdEntry) bool { return e.Name == name })
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown user %q", name)
+	}
+	return entry, nil
+}
@@ -0,0 +1,74 @@
+//go:build !windows
+
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPasswd = `# comment
+root:x:0:0:root:/root:/bin/bash
+al:x:1000:1000::/home/al:/bin/sh
+alice:x:1001:1001:Alice:/home/alice:/usr/bin/zsh
+broken:x:notanumber:0::/:/bin/sh
+`
+
+func withPasswd(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "passwd")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	saved := passwdPath
+	passwdPath = path
+	t.Cleanup(func() { passwdPath = saved })
+}
+
+func TestLookupUserByUID(t *testing.T) {
+	withPasswd(t, testPasswd)
+
+	// /home/al 是 /home/alice 的前缀，按 UID 查找不应混淆
+	entry, err := lookupUserByUID(1001)
+	if err != nil {
+		t.Fatalf("lookupUserByUID: %v", err)
+	}
+	if entry.Name != "alice" || entry.Shell != "/usr/bin/zsh" || entry.Home != "/home/alice" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if _, err := lookupUserByUID(4242); err == nil {
+		t.Fatal("expected error for unknown uid")
+	}
+}
+
+func TestLookupUserByName(t *testing.T) {
+	withPasswd(t, testPasswd)
+
+	entry, err := lookupUserByName("al")
+	if err != nil {
+		t.Fatalf("lookupUserByName: %v", err)
+	}
+	if entry.UID != 1000 || entry.GID != 1000 {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if _, err := lookupUserByName("broken"); err == nil {
+		t.Fatal("malformed entries should be skipped")
+	}
+}
+
+func TestResolveTerminalUserRequiresAllowList(t *testing.T) {
+	withPasswd(t, testPasswd+"nobody-test:x:65530:65530::/nonexistent:/bin/sh\n")
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+
+	flags.TerminalUsers = ""
+	_, _, err := resolveTerminalUser("nobody-test")
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expected user to be rejected, got %v", err)
+	}
+	if _, _, err := resolveTerminalUser("missing-user"); err == nil {
+		t.Fatal("expected unknown user to be rejected")
+	}
+}
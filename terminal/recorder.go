@@ -0,0 +1,297 @@
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/komari-monitor/komari-agent/dnsresolver"
+)
+
+const defaultRecordDir = "./recordings"
+
+// recordingMeta 会话元数据，写入 asciicast 头部的 komari 字段
+type recordingMeta struct {
+	RequestID string    `json:"request_id"`
+	Shell     string    `json:"shell"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+}
+
+// asciicastHeader asciicast v2 文件头
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Duration  float64           `json:"duration"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Komari    recordingMeta     `json:"komari"`
+}
+
+// recorder 将终端会话记录为 asciicast v2 格式。
+// 事件先写入临时文件，结束时补齐带结束时间和时长的文件头后生成最终文件。
+// 所有方法对 nil 接收者安全，未启用录制时可直接调用。
+type recorder struct {
+	mu          sync.Mutex
+	dir         string
+	path        string
+	tmp         *os.File
+	buf         *bufio.Writer
+	start       time.Time
+	width       int
+	height      int
+	meta        recordingMeta
+	recordInput bool
+	pending     map[string][]byte // 各事件类型尚未凑齐的 UTF-8 尾部字节
+	closed      bool
+}
+
+// newRecorder 按配置创建录制器，未启用录制时返回 nil
+func newRecorder(requestID, shell string, cols, rows int) *recorder {
+	if !flags.RecordTerminal {
+		return nil
+	}
+	dir := flags.RecordDir
+	if dir == "" {
+		dir = defaultRecordDir
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		log.Printf("Failed to create terminal recording directory: %v", err)
+		return nil
+	}
+	start := time.Now()
+	name := fmt.Sprintf("%s-%s.cast", start.Format("20060102-150405"), sanitizeRecordingName(requestID))
+	tmp, err := os.CreateTemp(dir, name+".*.part")
+	if err != nil {
+		log.Printf("Failed to create terminal recording: %v", err)
+		return nil
+	}
+	return &recorder{
+		dir:         dir,
+		path:        filepath.Join(dir, name),
+		tmp:         tmp,
+		buf:         bufio.NewWriter(tmp),
+		start:       start,
+		width:       cols,
+		height:      rows,
+		meta:        recordingMeta{RequestID: requestID, Shell: shell, StartedAt: start},
+		recordInput: flags.RecordTerminalInput,
+		pending:     make(map[string][]byte),
+	}
+}
+
+// Output 记录终端输出
+func (r *recorder) Output(p []byte) {
+	r.event("o", p)
+}
+
+// Input 记录用户输入，仅在开启输入录制时生效
+func (r *recorder) Input(p []byte) {
+	if r == nil || !r.recordInput {
+		return
+	}
+	r.event("i", p)
+}
+
+// Resize 记录终端尺寸变化
+func (r *recorder) Resize(cols, rows int) {
+	r.event("r", []byte(fmt.Sprintf("%dx%d", cols, rows)))
+}
+
+func (r *recorder) event(kind string, p []byte) {
+	if r == nil || len(p) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	data := append(r.pending[kind], p...)
+	// 保留被截断在读缓冲边界的 UTF-8 字符，待下一次数据到达后再写入
+	cut := incompleteUTF8Suffix(data)
+	r.pending[kind] = append([]byte(nil), data[len(data)-cut:]...)
+	data = data[:len(data)-cut]
+	if len(data) == 0 {
+		return
+	}
+	line, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), kind, string(data)})
+	if err != nil {
+		return
+	}
+	r.buf.Write(line)
+	r.buf.WriteByte('\n')
+}
+
+// Close 结束录制，生成最终的 .cast 文件并执行保留策略，按配置上传到面板
+func (r *recorder) Close() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	r.meta.EndedAt = time.Now()
+	err := r.finalize()
+	r.mu.Unlock()
+	if err != nil {
+		log.Printf("Failed to finalize terminal recording: %v", err)
+		return
+	}
+	log.Printf("Terminal session recorded to %s", r.path)
+	pruneRecordings(r.dir, flags.RecordMaxFiles, time.Duration(flags.RecordMaxAgeDays)*24*time.Hour)
+	if flags.RecordUpload {
+		go func() {
+			if err := uploadRecording(r.path, r.meta.RequestID); err != nil {
+				log.Printf("Failed to upload terminal recording: %v", err)
+			}
+		}()
+	}
+}
+
+func (r *recorder) finalize() error {
+	tmpName := r.tmp.Name()
+	defer os.Remove(tmpName)
+	if err := r.buf.Flush(); err != nil {
+		r.tmp.Close()
+		return err
+	}
+	if _, err := r.tmp.Seek(0, io.SeekStart); err != nil {
+		r.tmp.Close()
+		return err
+	}
+	defer r.tmp.Close()
+
+	out, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	header, err := json.Marshal(asciicastHeader{
+		Version:   2,
+		Width:     r.width,
+		Height:    r.height,
+		Timestamp: r.start.Unix(),
+		Duration:  r.meta.EndedAt.Sub(r.start).Seconds(),
+		Title:     "komari terminal " + r.meta.RequestID,
+		Env:       map[string]string{"SHELL": r.meta.Shell, "TERM": "xterm-256color"},
+		Komari:    r.meta,
+	})
+	if err != nil {
+		out.Close()
+		return err
+	}
+	if _, err := out.Write(append(header, '\n')); err != nil {
+		out.Close()
+		return err
+	}
+	if _, err := io.Copy(out, r.tmp); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// incompleteUTF8Suffix 返回 p 末尾不完整 UTF-8 序列的字节数
+func incompleteUTF8Suffix(p []byte) int {
+	for i := 1; i <= utf8.UTFMax-1 && i <= len(p); i++ {
+		c := p[len(p)-i]
+		if utf8.RuneStart(c) {
+			if !utf8.FullRune(p[len(p)-i:]) {
+				return i
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+func sanitizeRecordingName(s string) string {
+	if s == "" {
+		return "session"
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// pruneRecordings 删除超过保留天数的录制，并只保留最新的 maxFiles 个
+func pruneRecordings(dir string, maxFiles int, maxAge time.Duration) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type recording struct {
+		path    string
+		modTime time.Time
+	}
+	var recordings []recording
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".cast") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if maxAge > 0 && now.Sub(info.ModTime()) > maxAge {
+			os.Remove(path)
+			continue
+		}
+		recordings = append(recordings, recording{path: path, modTime: info.ModTime()})
+	}
+	if maxFiles <= 0 || len(recordings) <= maxFiles {
+		return
+	}
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].modTime.After(recordings[j].modTime)
+	})
+	for _, rec := range recordings[maxFiles:] {
+		os.Remove(rec.path)
+	}
+}
+
+// uploadRecording 将录制文件上传到面板
+func uploadRecording(path, requestID string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	endpoint := strings.TrimSuffix(flags.Endpoint, "/") + "/api/clients/terminal/recording?token=" + flags.Token + "&id=" + requestID
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-asciicast")
+	client := dnsresolver.GetHTTPClientWithPreference(60*time.Second, flags.PreferIPVersion)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upload failed: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package terminal
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func enableRecording(t *testing.T, dir string, recordInput bool) {
+	t.Helper()
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+	flags.RecordTerminal = true
+	flags.RecordTerminalInput = recordInput
+	flags.RecordDir = dir
+	flags.RecordMaxFiles = 0
+	flags.RecordMaxAgeDays = 0
+	flags.RecordUpload = false
+}
+
+func TestRecorderWritesAsciicastV2(t *testing.T) {
+	dir := t.TempDir()
+	enableRecording(t, dir, false)
+
+	rec := newRecorder("req-1", "/bin/bash", 80, 24)
+	if rec == nil {
+		t.Fatal("expected recorder when recording is enabled")
+	}
+	// “你” 被拆在两次读取之间，录制中应保持完整
+	ni := []byte("你")
+	rec.Output(append([]byte("hi "), ni[:1]...))
+	rec.Output(append(ni[1:], '\n'))
+	rec.Input([]byte("ls\n"))
+	rec.Resize(120, 40)
+	rec.Close()
+
+	f, err := os.Open(rec.path)
+	if err != nil {
+		t.Fatalf("open recording: %v", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		t.Fatal("missing header line")
+	}
+	var header asciicastHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		t.Fatalf("bad header: %v", err)
+	}
+	if header.Version != 2 || header.Width != 80 || header.Height != 24 {
+		t.Fatalf("unexpected header %+v", header)
+	}
+	if header.Komari.RequestID != "req-1" || header.Komari.Shell != "/bin/bash" || header.Komari.EndedAt.IsZero() {
+		t.Fatalf("unexpected session metadata %+v", header.Komari)
+	}
+
+	var output string
+	var kinds []string
+	for scanner.Scan() {
+		var ev []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("bad event line %q: %v", scanner.Text(), err)
+		}
+		kinds = append(kinds, ev[1].(string))
+		if ev[1] == "o" {
+			output += ev[2].(string)
+		}
+	}
+	if output != "hi 你\n" {
+		t.Fatalf("unexpected recorded output %q", output)
+	}
+	for _, k := range kinds {
+		if k == "i" {
+			t.Fatal("input should not be recorded unless enabled")
+		}
+	}
+	if kinds[len(kinds)-1] != "r" {
+		t.Fatalf("expected resize event last, got %v", kinds)
+	}
+}
+
+func TestRecorderDisabledIsNilSafe(t *testing.T) {
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+	flags.RecordTerminal = false
+
+	rec := newRecorder("req", "sh", 80, 24)
+	if rec != nil {
+		t.Fatal("expected nil recorder when recording is disabled")
+	}
+	rec.Output([]byte("x"))
+	rec.Input([]byte("x"))
+	rec.Resize(1, 1)
+	rec.Close()
+}
+
+func TestPruneRecordingsKeepsNewest(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"a.cast", "b.cast", "c.cast", "old.cast"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		mod := now.Add(-time.Duration(i) * time.Minute)
+		if name == "old.cast" {
+			mod = now.Add(-48 * time.Hour)
+		}
+		os.Chtimes(path, mod, mod)
+	}
+
+	pruneRecordings(dir, 2, 24*time.Hour)
+
+	for name, want := range map[string]bool{"a.cast": true, "b.cast": true, "c.cast": false, "old.cast": false} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists=%v, want %v", name, exists, want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package terminal
+
+// scrollbackSize 每个会话保留的终端输出字节数
+const scrollbackSize = 256 << 10
+
+// scrollback 固定容量的环形缓冲区，保存最近的终端输出。
+// total 记录累计写入的字节数，用作输出流中的偏移量。非并发安全，由会话锁保护。
+type scrollback struct {
+	buf   []byte
+	start int   // 最旧数据在 buf 中的位置
+	size  int   // 当前保存的字节数
+	total int64 // 累计写入的字节数
+}
+
+func newScrollback(capacity int) *scrollback {
+	return &scrollback{buf: make([]byte, capacity)}
+}
+
+// Write 追加输出，超出容量时覆盖最旧的数据
+func (b *scrollback) Write(p []byte) {
+	b.total += int64(len(p))
+	capacity := len(b.buf)
+	if len(p) >= capacity {
+		copy(b.buf, p[len(p)-capacity:])
+		b.start, b.size = 0, capacity
+		return
+	}
+	end := (b.start + b.size) % capacity
+	n := copy(b.buf[end:], p)
+	copy(b.buf, p[n:])
+	b.size += len(p)
+	if b.size > capacity {
+		b.start = (b.start + b.size - capacity) % capacity
+		b.size = capacity
+	}
+}
+
+// Offset 返回当前的输出流偏移量
+func (b *scrollback) Offset() int64 {
+	return b.total
+}
+
+// Since 返回自 offset 之后写入的输出；若部分数据已被覆盖，则返回缓冲区内全部数据
+func (b *scrollback) Since(offset int64) []byte {
+	n := int(b.total - offset)
+	if n <= 0 {
+		return nil
+	}
+	if n > b.size {
+		n = b.size
+	}
+	out := make([]byte, n)
+	capacity := len(b.buf)
+	from := (b.start + b.size - n) % capacity
+	copied := copy(out, b.buf[from:min(from+n, capacity)])
+	copy(out[copied:], b.buf[:n-copied])
+	return out
+}
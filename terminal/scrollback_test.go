@@ -0,0 +1,38 @@
+package terminal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestScrollbackSince(t *testing.T) {
+	b := newScrollback(8)
+	b.Write([]byte("abc"))
+	offset := b.Offset()
+	b.Write([]byte("def"))
+	if got := b.Since(offset); string(got) != "def" {
+		t.Fatalf("expected def, got %q", got)
+	}
+	if got := b.Since(b.Offset()); got != nil {
+		t.Fatalf("expected nothing after current offset, got %q", got)
+	}
+}
+
+func TestScrollbackWrapsAround(t *testing.T) {
+	b := newScrollback(8)
+	b.Write([]byte("012345"))
+	b.Write([]byte("6789"))
+	if got := b.Since(0); string(got) != "23456789" {
+		t.Fatalf("expected oldest data to be overwritten, got %q", got)
+	}
+	if got := b.Since(7); string(got) != "789" {
+		t.Fatalf("expected data since offset 7, got %q", got)
+	}
+	b.Write(bytes.Repeat([]byte("x"), 20))
+	if got := b.Since(0); !bytes.Equal(got, bytes.Repeat([]byte("x"), 8)) {
+		t.Fatalf("expected buffer filled by large write, got %q", got)
+	}
+	if b.Offset() != 30 {
+		t.Fatalf("expected offset 30, got %d", b.Offset())
+	}
+}
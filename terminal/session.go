@@ -0,0 +1,499 @@
+package terminal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sessionCheckInterval 检查空闲超时与存活时间的周期
+const sessionCheckInterval = 10 * time.Second
+
+// 会话被关闭的原因
+const (
+	closeReasonIdle     = "idle timeout"
+	closeReasonLifetime = "session lifetime exceeded"
+	closeReasonKilled   = "killed by dashboard"
+	closeReasonDetached = "connection closed"
+	closeReasonExited   = "shell exited"
+)
+
+var (
+	ErrTooManySessions = errors.New("too many active terminal sessions")
+	ErrSessionExists   = errors.New("terminal session already exists")
+	ErrSessionNotFound = errors.New("terminal session not found")
+)
+
+// SessionInfo 活动终端会话的概要信息
+type SessionInfo struct {
+	ID           string    `json:"id"`
+	Shell        string    `json:"shell"`
+	StartedAt    time.Time `json:"started_at"`
+	LastActivity time.Time `json:"last_activity"`
+	IdleSeconds  int64     `json:"idle_seconds"`
+	Attached     bool      `json:"attached"`
+	Viewers      int       `json:"viewers"`
+	Resumable    bool      `json:"resumable"`
+}
+
+// session 终端会话。会话持有 PTY、录制器与输出缓冲，生命周期独立于 WebSocket 连接：
+// 可恢复的会话在连接断开后保留一段宽限期，期间携带相同会话 ID 的请求可以重新接入。
+// 一个会话同时只有一个可输入的操作者（writer），其余连接为只读观看者（viewer），
+// 操作权可以在参与者之间移交。
+type session struct {
+	id           string
+	resumable    bool
+	startedAt    time.Time
+	lastActivity atomic.Int64 // UnixNano
+	kill         chan string
+	killOnce     sync.Once
+	started      chan struct{} // 终端启动后关闭，之前不允许其他连接接入
+	done         chan struct{} // 会话结束后关闭
+
+	mu           sync.Mutex
+	shell        string
+	impl         *terminalImpl
+	rec          *recorder
+	output       *scrollback
+	writer       *attachment // 当前操作者，无人操作时为 nil
+	viewers      map[*attachment]struct{}
+	nextID       int   // 未指定 ID 的参与者的编号
+	cols, rows   int   // 当前终端尺寸，供观看者同步
+	detachOffset int64 // 操作者断开时的输出偏移，重新接入时从此处回放
+	detachTimer  *time.Timer
+}
+
+func (s *session) touch() {
+	if s == nil {
+		return
+	}
+	s.lastActivity.Store(time.Now().UnixNano())
+}
+
+func (s *session) idle(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, s.lastActivity.Load()))
+}
+
+// expired 按配置的空闲超时与最长存活时间判断会话是否应被关闭，返回关闭原因
+func (s *session) expired(now time.Time) string {
+	if flags.TerminalIdleTimeout > 0 && s.idle(now) > time.Duration(flags.TerminalIdleTimeout)*time.Minute {
+		return closeReasonIdle
+	}
+	if flags.TerminalMaxLifetime > 0 && now.Sub(s.startedAt) > time.Duration(flags.TerminalMaxLifetime)*time.Minute {
+		return closeReasonLifetime
+	}
+	return ""
+}
+
+func (s *session) close(reason string) {
+	s.killOnce.Do(func() {
+		s.kill <- reason
+	})
+}
+
+func (s *session) info(now time.Time) SessionInfo {
+	last := time.Unix(0, s.lastActivity.Load())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SessionInfo{
+		ID:           s.id,
+		Shell:        s.shell,
+		StartedAt:    s.startedAt,
+		LastActivity: last,
+		IdleSeconds:  int64(now.Sub(last).Seconds()),
+		Attached:     s.writer != nil,
+		Viewers:      len(s.viewers),
+		Resumable:    s.resumable,
+	}
+}
+
+// start 在终端启动后开始转发输出并监控会话生命周期
+func (s *session) start(impl *terminalImpl, rec *recorder, cols, rows int) {
+	s.mu.Lock()
+	s.cols, s.rows = cols, rows
+	s.impl = impl
+	s.shell = impl.shell
+	s.rec = rec
+	s.mu.Unlock()
+	close(s.started)
+
+	go s.pumpOutput()
+	go s.run()
+}
+
+// pumpOutput 持续读取终端输出，写入录制与缓冲区，并转发给当前接入的连接
+func (s *session) pumpOutput() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := s.impl.term.Read(buf)
+		if err != nil {
+			s.close(closeReasonExited)
+			return
+		}
+		s.touch()
+		s.rec.Output(buf[:n])
+		s.mu.Lock()
+		s.output.Write(buf[:n])
+		end := s.output.Offset()
+		for v := range s.viewers {
+			v.offer(buf[:n], end)
+		}
+		writer := s.writer
+		s.mu.Unlock()
+		// 操作者连接拥塞或请求暂停时在会话锁外阻塞，暂停读取终端输出
+		writer.enqueue(buf[:n], end)
+	}
+}
+
+// resync 为落后的连接从缓冲区补齐输出
+func (s *session) resync(att *attachment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	att.catchUp(s.output)
+}
+
+// run 等待会话结束条件，并负责回收终端资源
+func (s *session) run() {
+	ticker := time.NewTicker(sessionCheckInterval)
+	defer ticker.Stop()
+	var reason string
+	for reason == "" {
+		select {
+		case reason = <-s.kill:
+		case now := <-ticker.C:
+			reason = s.expired(now)
+		}
+	}
+	log.Printf("Closing terminal session %s: %s", s.id, reason)
+
+	s.mu.Lock()
+	participants := s.viewerList()
+	if s.writer != nil {
+		participants = append(participants, s.writer)
+	}
+	s.writer = nil
+	s.viewers = nil
+	if s.detachTimer != nil {
+		s.detachTimer.Stop()
+	}
+	s.mu.Unlock()
+	for _, att := range participants {
+		att.closeWithReason(reason)
+	}
+
+	gracefulShutdown(s.impl.term)
+	s.impl.term.Close()
+	s.rec.Close()
+	sessions.unregister(s)
+	close(s.done)
+}
+
+// attach 将连接接入会话。
+// 观看者收到缓冲区中的输出以重建当前屏幕；操作者重新接入时回放断开期间错过的输出，
+// 已有操作者时由新连接接管，原操作者降为观看者。
+func (s *session) attach(att *attachment, viewer bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if att.id == "" {
+		s.nextID++
+		att.id = fmt.Sprintf("participant-%d", s.nextID)
+	}
+	// 回放内容在持锁期间入队，保证先于后续实时输出发出
+	if viewer {
+		att.prime(s.screenState(), s.output.Offset(), s.resync)
+		att.setViewer(true)
+		s.viewers[att] = struct{}{}
+		s.stopDetachTimer()
+	} else {
+		if s.writer != nil {
+			att.prime(s.screenState(), s.output.Offset(), s.resync)
+			s.demote(s.writer)
+		} else {
+			att.prime(s.output.Since(s.detachOffset), s.output.Offset(), s.resync)
+		}
+		s.setWriter(att)
+	}
+	s.broadcastRoles()
+}
+
+// screenState 返回用于重建屏幕的输出。缓冲区已回绕时从第一个换行之后开始，避免从半个控制序列开始回放
+func (s *session) screenState() []byte {
+	out := s.output.Since(0)
+	if s.output.Offset() > int64(len(out)) {
+		if i := bytes.IndexByte(out, '\n'); i >= 0 {
+			out = out[i+1:]
+		}
+	}
+	return out
+}
+
+// setWriter 设置操作者，需持有 s.mu
+func (s *session) setWriter(att *attachment) {
+	att.setViewer(false)
+	delete(s.viewers, att)
+	s.writer = att
+	s.stopDetachTimer()
+}
+
+// demote 将操作者降为观看者，需持有 s.mu。观看者不再阻塞终端输出
+func (s *session) demote(att *attachment) {
+	att.setViewer(true)
+	s.viewers[att] = struct{}{}
+}
+
+func (s *session) stopDetachTimer() {
+	if s.detachTimer != nil {
+		s.detachTimer.Stop()
+		s.detachTimer = nil
+	}
+}
+
+func (s *session) viewerList() []*attachment {
+	list := make([]*attachment, 0, len(s.viewers))
+	for v := range s.viewers {
+		list = append(list, v)
+	}
+	return list
+}
+
+func (s *session) isWriter(att *attachment) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writer == att
+}
+
+// roleNotice 告知参与者其角色与会话状态
+type roleNotice struct {
+	Type        string `json:"type"`
+	Participant string `json:"participant"`
+	Role        string `json:"role"`
+	Writer      string `json:"writer,omitempty"`
+	Viewers     int    `json:"viewers"`
+	Cols        int    `json:"cols"`
+	Rows        int    `json:"rows"`
+}
+
+// broadcastRoles 向所有参与者发送最新的角色信息，需持有 s.mu
+func (s *session) broadcastRoles() {
+	notice := roleNotice{Type: "control", Viewers: len(s.viewers), Cols: s.cols, Rows: s.rows}
+	if s.writer != nil {
+		notice.Writer = s.writer.id
+		n := notice
+		n.Participant, n.Role = s.writer.id, "writer"
+		s.writer.notify(n)
+	}
+	for v := range s.viewers {
+		n := notice
+		n.Participant, n.Role = v.id, "viewer"
+		v.notify(n)
+	}
+}
+
+// requestControl 观看者请求操作权：无人操作时直接获得，否则通知当前操作者
+func (s *session) requestControl(att *attachment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.viewers[att]; !ok {
+		return
+	}
+	if s.writer == nil {
+		s.setWriter(att)
+		s.broadcastRoles()
+		return
+	}
+	s.writer.notify(map[string]string{"type": "control_request", "from": att.id})
+}
+
+// grantControl 操作者将操作权移交给指定的观看者，自己降为观看者
+func (s *session) grantControl(att *attachment, to string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writer != att {
+		return
+	}
+	for v := range s.viewers {
+		if v.id == to {
+			s.demote(att)
+			s.setWriter(v)
+			s.broadcastRoles()
+			return
+		}
+	}
+}
+
+// releaseControl 操作者放弃操作权，会话暂时无人操作，任一观看者可请求接管
+func (s *session) releaseControl(att *attachment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writer != att {
+		return
+	}
+	s.writer = nil
+	s.demote(att)
+	s.detachOffset = s.output.Offset()
+	s.broadcastRoles()
+}
+
+// resize 调整终端尺寸并同步给观看者，只有操作者可以调整，尺寸上限与初始尺寸相同
+func (s *session) resize(att *attachment, cols, rows int) {
+	cols, rows = min(cols, maxTermSize), min(rows, maxTermSize)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writer != att {
+		return
+	}
+	s.cols, s.rows = cols, rows
+	s.impl.term.Resize(cols, rows)
+	s.rec.Resize(cols, rows)
+	s.broadcastRoles()
+}
+
+// detach 在连接断开时调用。仍有其他参与者时会话继续，观看者可以请求接管；
+// 最后一个参与者离开后，不可恢复的会话立即关闭，否则在宽限期内无人接入时关闭
+func (s *session) detach(att *attachment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.viewers[att]; ok {
+		delete(s.viewers, att)
+	} else if s.writer == att {
+		s.writer = nil
+		// 从实际送达的位置回放，断开时队列中未发出的输出也能在重新接入后补上
+		s.detachOffset = att.deliveredOffset()
+	} else {
+		return
+	}
+	if s.attached() {
+		s.broadcastRoles()
+		return
+	}
+	grace := time.Duration(flags.TerminalResumeGrace) * time.Second
+	if !s.resumable || grace <= 0 {
+		s.close(closeReasonDetached)
+		return
+	}
+	log.Printf("Terminal session %s detached, keeping it for %s", s.id, grace)
+	s.detachTimer = time.AfterFunc(grace, func() {
+		s.mu.Lock()
+		attached := s.attached()
+		s.mu.Unlock()
+		if !attached {
+			s.close(closeReasonDetached)
+		}
+	})
+}
+
+// attached 是否仍有连接接入会话，需持有 s.mu
+func (s *session) attached() bool {
+	return s.writer != nil || len(s.viewers) > 0
+}
+
+// sessionRegistry 管理所有活动终端会话
+type sessionRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+var sessions = &sessionRegistry{sessions: make(map[string]*session)}
+
+// register 创建新会话。resumable 为 true 时断开后保留会话以供重新接入
+func (r *sessionRegistry) register(id string, resumable bool) (*session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id != "" {
+		if _, ok := r.sessions[id]; ok {
+			return nil, ErrSessionExists
+		}
+	}
+	if flags.TerminalMaxSessions > 0 && len(r.sessions) >= flags.TerminalMaxSessions {
+		return nil, fmt.Errorf("%w (limit %d)", ErrTooManySessions, flags.TerminalMaxSessions)
+	}
+	if id == "" {
+		id = fmt.Sprintf("local-%d", time.Now().UnixNano())
+	}
+	now := time.Now()
+	s := &session{
+		id:        id,
+		resumable: resumable,
+		startedAt: now,
+		kill:      make(chan string, 1),
+		started:   make(chan struct{}),
+		done:      make(chan struct{}),
+		output:    newScrollback(scrollbackSize),
+		viewers:   make(map[*attachment]struct{}),
+	}
+	s.lastActivity.Store(now.UnixNano())
+	r.sessions[id] = s
+	return s, nil
+}
+
+func (r *sessionRegistry) unregister(s *session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions[s.id] == s {
+		delete(r.sessions, s.id)
+	}
+}
+
+func (r *sessionRegistry) get(id string) *session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sessions[id]
+}
+
+// getStarted 返回已启动终端的会话。会话在启动 shell 之前就已登记以占用 ID 与名额，
+// 此时接入的连接还无法读写终端
+func (r *sessionRegistry) getStarted(id string) *session {
+	s := r.get(id)
+	if s == nil {
+		return nil
+	}
+	select {
+	case <-s.started:
+		return s
+	default:
+		return nil
+	}
+}
+
+func (r *sessionRegistry) all() []*session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	all := make([]*session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		all = append(all, s)
+	}
+	return all
+}
+
+func (r *sessionRegistry) list() []SessionInfo {
+	now := time.Now()
+	all := r.all()
+	infos := make([]SessionInfo, 0, len(all))
+	for _, s := range all {
+		infos = append(infos, s.info(now))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].StartedAt.Before(infos[j].StartedAt)
+	})
+	return infos
+}
+
+// ListSessions 返回当前所有活动终端会话
+func ListSessions() []SessionInfo {
+	return sessions.list()
+}
+
+// KillSession 关闭指定的终端会话
+func KillSession(id string) error {
+	s := sessions.get(id)
+	if s == nil {
+		return ErrSessionNotFound
+	}
+	s.close(closeReasonKilled)
+	return nil
+}
@@ -0,0 +1,294 @@
+package terminal
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func withSessionLimits(t *testing.T, maxSessions, idleMinutes, lifetimeMinutes int) {
+	t.Helper()
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+	flags.TerminalMaxSessions = maxSessions
+	flags.TerminalIdleTimeout = idleMinutes
+	flags.TerminalMaxLifetime = lifetimeMinutes
+}
+
+func TestSessionRegistryEnforcesLimit(t *testing.T) {
+	withSessionLimits(t, 2, 0, 0)
+	r := &sessionRegistry{sessions: make(map[string]*session)}
+
+	a, err := r.register("a", false)
+	if err != nil {
+		t.Fatalf("register a: %v", err)
+	}
+	if _, err := r.register("a", false); !errors.Is(err, ErrSessionExists) {
+		t.Fatalf("expected duplicate id to be rejected, got %v", err)
+	}
+	if _, err := r.register("b", false); err != nil {
+		t.Fatalf("register b: %v", err)
+	}
+	if _, err := r.register("c", false); !errors.Is(err, ErrTooManySessions) {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+	r.unregister(a)
+	if _, err := r.register("c", false); err != nil {
+		t.Fatalf("register after unregister: %v", err)
+	}
+	if got := len(r.list()); got != 2 {
+		t.Fatalf("expected 2 sessions listed, got %d", got)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	withSessionLimits(t, 0, 5, 60)
+	r := &sessionRegistry{sessions: make(map[string]*session)}
+	s, _ := r.register("x", false)
+
+	now := time.Now()
+	if reason := s.expired(now); reason != "" {
+		t.Fatalf("fresh session should not expire, got %q", reason)
+	}
+	if reason := s.expired(now.Add(6 * time.Minute)); reason != closeReasonIdle {
+		t.Fatalf("expected idle expiry, got %q", reason)
+	}
+	s.lastActivity.Store(now.Add(61 * time.Minute).UnixNano())
+	if reason := s.expired(now.Add(61 * time.Minute)); reason != closeReasonLifetime {
+		t.Fatalf("expected lifetime expiry, got %q", reason)
+	}
+}
+
+func TestKillSession(t *testing.T) {
+	withSessionLimits(t, 0, 0, 0)
+	s, err := sessions.register("kill-me", false)
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	defer sessions.unregister(s)
+
+	if err := KillSession("missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if err := KillSession("kill-me"); err != nil {
+		t.Fatalf("KillSession: %v", err)
+	}
+	// 重复关闭不应阻塞
+	if err := KillSession("kill-me"); err != nil {
+		t.Fatalf("second KillSession: %v", err)
+	}
+	select {
+	case reason := <-s.kill:
+		if reason != closeReasonKilled {
+			t.Fatalf("unexpected reason %q", reason)
+		}
+	default:
+		t.Fatal("expected kill signal")
+	}
+}
+
+func TestDetachKeepsResumableSession(t *testing.T) {
+	withSessionLimits(t, 0, 0, 0)
+	flags.TerminalResumeGrace = 60
+	r := &sessionRegistry{sessions: make(map[string]*session)}
+
+	s, _ := r.register("resumable", true)
+	// 断开时 "before" 只有前 3 个字节送达，其余仍在队列中
+	att := &attachment{delivered: 3}
+	s.writer = att
+	s.output.Write([]byte("before"))
+	s.detach(att)
+	select {
+	case reason := <-s.kill:
+		t.Fatalf("resumable session closed on detach: %q", reason)
+	default:
+	}
+	s.output.Write([]byte(" missed"))
+	if got := s.output.Since(s.detachOffset); string(got) != "ore missed" {
+		t.Fatalf("expected undelivered and missed output to be replayable, got %q", got)
+	}
+	s.detachTimer.Stop()
+
+	plain, _ := r.register("plain", false)
+	att = &attachment{}
+	plain.writer = att
+	plain.detach(att)
+	select {
+	case reason := <-plain.kill:
+		if reason != closeReasonDetached {
+			t.Fatalf("unexpected reason %q", reason)
+		}
+	default:
+		t.Fatal("expected non-resumable session to close on detach")
+	}
+}
+
+// readNotice 读取下一条文本控制通知
+func readNotice(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		typ, p, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read notice: %v", err)
+		}
+		if typ != websocket.TextMessage {
+			continue
+		}
+		var notice map[string]interface{}
+		if err := json.Unmarshal(p, &notice); err != nil {
+			t.Fatalf("bad notice %q: %v", p, err)
+		}
+		return notice
+	}
+}
+
+func TestSharedSessionViewersAndHandover(t *testing.T) {
+	withSessionLimits(t, 0, 0, 0)
+	r := &sessionRegistry{sessions: make(map[string]*session)}
+	s, _ := r.register("shared", true)
+	s.output.Write([]byte("$ uptime\n"))
+
+	writer, writerConn := newTestAttachment(t)
+	s.attach(writer, false)
+	if n := readNotice(t, writerConn); n["role"] != "writer" {
+		t.Fatalf("expected writer role, got %v", n)
+	}
+
+	viewer, viewerConn := newTestAttachment(t)
+	s.attach(viewer, true)
+	if n := readNotice(t, viewerConn); n["role"] != "viewer" || n["writer"] != writer.id {
+		t.Fatalf("unexpected viewer notice %v", n)
+	}
+	viewerConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if typ, p, err := viewerConn.ReadMessage(); err != nil || typ != websocket.BinaryMessage || string(p) != "$ uptime\n" {
+		t.Fatalf("expected screen state for viewer, got %q (%v)", p, err)
+	}
+
+	s.requestControl(viewer)
+	for {
+		n := readNotice(t, writerConn)
+		if n["type"] == "control_request" {
+			if n["from"] != viewer.id {
+				t.Fatalf("unexpected control request %v", n)
+			}
+			break
+		}
+	}
+
+	s.grantControl(writer, viewer.id)
+	if !s.isWriter(viewer) || s.isWriter(writer) {
+		t.Fatal("expected control to move to the viewer")
+	}
+	s.detach(viewer)
+	select {
+	case reason := <-s.kill:
+		t.Fatalf("session closed while a participant remains: %q", reason)
+	default:
+	}
+	if info := s.info(time.Now()); info.Attached || info.Viewers != 1 {
+		t.Fatalf("unexpected session info %+v", info)
+	}
+}
+
+// chanTerminal 从通道读取输出的测试终端，通道无缓冲，可以观察会话是否仍在读取
+type chanTerminal struct {
+	out    chan []byte
+	closed chan struct{}
+	once   sync.Once
+	size   [2]int // 最近一次 Resize 的尺寸
+}
+
+func newChanTerminal() *chanTerminal {
+	return &chanTerminal{out: make(chan []byte), closed: make(chan struct{})}
+}
+
+func (c *chanTerminal) Read(p []byte) (int, error) {
+	select {
+	case b := <-c.out:
+		return copy(p, b), nil
+	case <-c.closed:
+		return 0, io.EOF
+	}
+}
+
+func (c *chanTerminal) Write(p []byte) (int, error) { return len(p), nil }
+func (c *chanTerminal) Resize(cols, rows int) error {
+	c.size = [2]int{cols, rows}
+	return nil
+}
+func (c *chanTerminal) Wait() error { return nil }
+func (c *chanTerminal) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func TestPausedWriterStopsReadingTerminal(t *testing.T) {
+	withSessionLimits(t, 0, 0, 0)
+	r := &sessionRegistry{sessions: make(map[string]*session)}
+	s, _ := r.register("backpressure", false)
+	term := newChanTerminal()
+	s.start(&terminalImpl{term: term}, nil, 80, 24)
+	t.Cleanup(func() { s.close(closeReasonKilled) })
+
+	writer, _ := newTestAttachment(t)
+	s.attach(writer, false)
+	writer.setPaused(true)
+
+	// 第一块输出被读取后阻塞在操作者的队列上，终端不再被读取
+	term.out <- []byte("first")
+	select {
+	case term.out <- []byte("second"):
+		t.Fatal("terminal was read while the writer was paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	writer.setPaused(false)
+	select {
+	case term.out <- []byte("second"):
+	case <-time.After(2 * time.Second):
+		t.Fatal("terminal reading did not resume")
+	}
+}
+
+func TestStartingSessionIsNotAttachable(t *testing.T) {
+	withSessionLimits(t, 0, 0, 0)
+	r := &sessionRegistry{sessions: make(map[string]*session)}
+	s, _ := r.register("starting", true)
+
+	// shell 启动期间 ID 已被占用，但观看与恢复请求找不到该会话
+	if _, err := r.register("starting", true); !errors.Is(err, ErrSessionExists) {
+		t.Fatalf("expected id to be reserved, got %v", err)
+	}
+	if r.getStarted("starting") != nil {
+		t.Fatal("session should not be attachable before its terminal starts")
+	}
+
+	term := newChanTerminal()
+	s.start(&terminalImpl{term: term}, nil, 80, 24)
+	t.Cleanup(func() { s.close(closeReasonKilled) })
+	if r.getStarted("starting") != s {
+		t.Fatal("expected started session to be attachable")
+	}
+}
+
+func TestResizeClampsToMaxTermSize(t *testing.T) {
+	withSessionLimits(t, 0, 0, 0)
+	r := &sessionRegistry{sessions: make(map[string]*session)}
+	s, _ := r.register("resize", false)
+	term := newChanTerminal()
+	s.impl = &terminalImpl{term: term}
+	att := &attachment{}
+	att.cond = sync.NewCond(&att.mu)
+	s.writer = att
+
+	s.resize(att, 50000, 30)
+	if term.size != [2]int{maxTermSize, 30} || s.cols != maxTermSize {
+		t.Fatalf("expected size clamped to %d columns, got %v (session %dx%d)", maxTermSize, term.size, s.cols, s.rows)
+	}
+}
@@ -3,6 +3,11 @@ package terminal
 import (
 	"encoding/json"
 	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -21,6 +26,103 @@ type Terminal interface {
 	Wait() error
 }
 
+// 终端初始尺寸的默认值与上限
+const (
+	defaultCols = 80
+	defaultRows = 24
+	maxTermSize = 1000
+)
+
+// modeView 以只读观看者身份加入会话
+const modeView = "view"
+
+// Options 终端会话参数，字段与 agent.terminal.request 的参数一一对应
+type Options struct {
+	RequestID  string            `json:"request_id"`  // 面板下发的终端请求 ID
+	SessionID  string            `json:"session_id"`  // 可恢复会话 ID，非空时断开后保留会话，携带相同 ID 的请求会重新接入
+	Mode       string            `json:"mode"`        // 为 view 时以只读观看者身份加入已有会话
+	User       string            `json:"user"`        // 目标用户，需在 --terminal-users 中，切换用户要求以 root 运行
+	Shell      string            `json:"shell"`       // 指定 shell，需在 --terminal-shells 中
+	WorkingDir string            `json:"working_dir"` // 工作目录，切换用户时默认为其主目录
+	Env        map[string]string `json:"env"`         // 附加环境变量
+	Cols       int               `json:"cols"`        // 初始列数
+	Rows       int               `json:"rows"`        // 初始行数
+}
+
+// size 返回校验后的初始终端尺寸
+func (o Options) size() (cols, rows int) {
+	cols, rows = o.Cols, o.Rows
+	if cols <= 0 || rows <= 0 {
+		return defaultCols, defaultRows
+	}
+	return min(cols, maxTermSize), min(rows, maxTermSize)
+}
+
+// splitList 解析逗号分隔的配置列表
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// userAllowed 检查用户是否在允许切换的用户列表中
+func userAllowed(name string) bool {
+	return slices.Contains(splitList(flags.TerminalUsers), name)
+}
+
+// shellAllowed 检查 shell 是否在允许的列表中。列表项为完整路径时需精确匹配，
+// 为程序名时只匹配同样以程序名指定（从 PATH 查找）的请求
+func shellAllowed(shell string) bool {
+	return slices.Contains(splitList(flags.TerminalShells), shell)
+}
+
+// mergeEnv 以 KEY=VALUE 形式合并环境变量，后出现的同名变量覆盖之前的值
+func mergeEnv(base []string, extra map[string]string) ([]string, error) {
+	keys := make([]string, 0, len(extra))
+	for key, value := range extra {
+		if key == "" || strings.ContainsAny(key, "=\x00") || strings.Contains(value, "\x00") {
+			return nil, fmt.Errorf("invalid environment variable %q", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	index := make(map[string]int, len(base)+len(keys))
+	merged := make([]string, 0, len(base)+len(keys))
+	set := func(key, kv string) {
+		if i, ok := index[key]; ok {
+			merged[i] = kv
+			return
+		}
+		index[key] = len(merged)
+		merged = append(merged, kv)
+	}
+	for _, kv := range base {
+		key, _, _ := strings.Cut(kv, "=")
+		set(key, kv)
+	}
+	for _, key := range keys {
+		set(key, key+"="+extra[key])
+	}
+	return merged, nil
+}
+
+// checkWorkingDir 确认工作目录存在且是目录
+func checkWorkingDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("invalid working directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid working directory: %s is not a directory", dir)
+	}
+	return nil
+}
+
 // terminalImpl 封装终端和平台特定逻辑
 type terminalImpl struct {
 	shell      string
@@ -28,45 +130,72 @@ type terminalImpl struct {
 	term       Terminal
 }
 
-// StartTerminal 启动终端并处理 WebSocket 通信
-func StartTerminal(conn *websocket.Conn) {
+// StartTerminal 启动终端并处理 WebSocket 通信，连接断开后返回
+func StartTerminal(conn *websocket.Conn, opts Options) {
 	if flags.DisableWebSsh {
 		conn.WriteMessage(websocket.TextMessage, []byte("\n\nWeb SSH is disabled. Enable it by running without the --disable-web-ssh flag."))
 		conn.Close()
 		return
 	}
-	impl, err := newTerminalImpl()
-	if err != nil {
-		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("Error: %v\r\n", err)))
+
+	if opts.Mode == modeView {
+		sess := sessions.getStarted(opts.SessionID)
+		if opts.SessionID == "" || sess == nil {
+			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("Error: %v\r\n", ErrSessionNotFound)))
+			conn.Close()
+			return
+		}
+		log.Printf("Viewer joined terminal session %s", sess.id)
+		serveAttachment(sess, conn, opts.RequestID, true)
 		return
 	}
 
-	errChan := make(chan error, 3) // 增加容量以容纳多个错误源
-	done := make(chan struct{})
+	id, resumable := opts.SessionID, opts.SessionID != ""
+	if !resumable {
+		id = opts.RequestID
+	}
+	// 重新接入仍在宽限期内的会话
+	if resumable {
+		if sess := sessions.getStarted(id); sess != nil {
+			log.Printf("Resuming terminal session %s", id)
+			serveAttachment(sess, conn, opts.RequestID, false)
+			return
+		}
+	}
 
-	defer func() {
-		gracefulShutdown(impl.term)
-		impl.term.Close()
+	sess, err := sessions.register(id, resumable)
+	if err != nil {
+		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("Error: %v\r\n", err)))
 		conn.Close()
-		close(done)
-	}()
-
-	// 从 WebSocket 读取消息并写入终端
-	go handleWebSocketInput(conn, impl.term, errChan, done)
+		return
+	}
 
-	// 从终端读取输出并写入 WebSocket
-	go handleTerminalOutput(conn, impl.term, errChan, done)
+	impl, err := newTerminalImpl(opts)
+	if err != nil {
+		sessions.unregister(sess)
+		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("Error: %v\r\n", err)))
+		conn.Close()
+		return
+	}
+	cols, rows := opts.size()
+	sess.start(impl, newRecorder(opts.RequestID, impl.shell, cols, rows), cols, rows)
+	serveAttachment(sess, conn, opts.RequestID, false)
+}
 
-	// 等待终端进程结束或出现错误
+// serveAttachment 将连接接入会话并处理输入，直到连接断开或会话结束
+func serveAttachment(sess *session, conn *websocket.Conn, participantID string, viewer bool) {
+	att := newAttachment(participantID, conn)
+	sess.attach(att, viewer)
+	err := handleWebSocketInput(att, sess)
 	select {
-	case err := <-errChan:
-		// WebSocket 连接断开或出现错误
+	case <-sess.done:
+	default:
 		if err != nil {
-			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("\r\nConnection error: %v\r\n", err)))
+			log.Printf("Terminal session %s connection closed: %v", sess.id, err)
 		}
-	case <-done:
-		// 已经被其他地方关闭
+		sess.detach(att)
 	}
+	att.close()
 }
 
 // gracefulShutdown 尝试优雅地关闭终端
@@ -89,75 +218,60 @@ func gracefulShutdown(term Terminal) {
 	time.Sleep(100 * time.Millisecond)
 }
 
-// handleWebSocketInput 处理 WebSocket 输入
-func handleWebSocketInput(conn *websocket.Conn, term Terminal, errChan chan<- error, done <-chan struct{}) {
+// handleWebSocketInput 处理 WebSocket 输入，返回导致连接结束的错误。
+// 只有操作者的输入与尺寸调整会生效，观看者只能使用操作权与流控相关的消息
+func handleWebSocketInput(att *attachment, sess *session) error {
+	term, rec := sess.impl.term, sess.rec
 	for {
-		select {
-		case <-done:
-			return
-		default:
-		}
-
-		t, p, err := conn.ReadMessage()
+		t, p, err := att.conn.ReadMessage()
 		if err != nil {
-			select {
-			case errChan <- err:
-			default:
-			}
-			return
+			return err
 		}
+		sess.touch()
+		writer := sess.isWriter(att)
 		if t == websocket.TextMessage {
 			var cmd struct {
 				Type  string `json:"type"`
 				Cols  int    `json:"cols,omitempty"`
 				Rows  int    `json:"rows,omitempty"`
 				Input string `json:"input,omitempty"`
+				To    string `json:"to,omitempty"`
 			}
 			if err := json.Unmarshal(p, &cmd); err == nil {
+				switch cmd.Type {
+				case "request_control":
+					sess.requestControl(att)
+				case "grant_control":
+					sess.grantControl(att, cmd.To)
+				case "release_control":
+					sess.releaseControl(att)
+				case "pause":
+					att.setPaused(true)
+				case "resume":
+					att.setPaused(false)
+				}
+				if !writer {
+					continue
+				}
 				switch cmd.Type {
 				case "resize":
 					if cmd.Cols > 0 && cmd.Rows > 0 {
-						term.Resize(cmd.Cols, cmd.Rows)
+						sess.resize(att, cmd.Cols, cmd.Rows)
 					}
 				case "input":
 					if cmd.Input != "" {
 						term.Write([]byte(cmd.Input))
+						rec.Input([]byte(cmd.Input))
 					}
 				}
-			} else {
+			} else if writer {
 				term.Write(p)
+				rec.Input(p)
 			}
 		}
-		if t == websocket.BinaryMessage {
+		if t == websocket.BinaryMessage && writer {
 			term.Write(p)
-		}
-	}
-}
-
-// handleTerminalOutput 处理终端输出
-func handleTerminalOutput(conn *websocket.Conn, term Terminal, errChan chan<- error, done <-chan struct{}) {
-	buf := make([]byte, 4096)
-	for {
-		select {
-		case <-done:
-			return
-		default:
-		}
-
-		n, err := term.Read(buf)
-		if err != nil {
-			select {
-			case errChan <- err:
-			default:
-			}
-			return
-		}
-		if err := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
-			select {
-			case errChan <- err:
-			default:
-			}
-			return
+			rec.Input(p)
 		}
 	}
 }
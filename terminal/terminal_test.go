@@ -0,0 +1,60 @@
+package terminal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOptionsSize(t *testing.T) {
+	cases := []struct {
+		cols, rows         int
+		wantCols, wantRows int
+	}{
+		{0, 0, defaultCols, defaultRows},
+		{120, 0, defaultCols, defaultRows},
+		{120, 40, 120, 40},
+		{5000, 40, maxTermSize, 40},
+	}
+	for _, c := range cases {
+		cols, rows := Options{Cols: c.cols, Rows: c.rows}.size()
+		if cols != c.wantCols || rows != c.wantRows {
+			t.Errorf("size(%d, %d) = %d, %d; want %d, %d", c.cols, c.rows, cols, rows, c.wantCols, c.wantRows)
+		}
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	got, err := mergeEnv([]string{"A=1", "TERM=dumb", "A=2"}, map[string]string{"TERM": "xterm", "B": "x=y"})
+	if err != nil {
+		t.Fatalf("mergeEnv: %v", err)
+	}
+	want := []string{"A=2", "TERM=xterm", "B=x=y"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if _, err := mergeEnv(nil, map[string]string{"BAD=KEY": "1"}); err == nil {
+		t.Fatal("expected invalid key to be rejected")
+	}
+}
+
+func TestShellAllowed(t *testing.T) {
+	saved := *flags
+	t.Cleanup(func() { *flags = saved })
+
+	flags.TerminalShells = ""
+	if shellAllowed("bash") {
+		t.Fatal("no shell should be allowed by default")
+	}
+	flags.TerminalShells = "bash, /usr/bin/fish"
+	for shell, want := range map[string]bool{
+		"bash":           true,
+		"/usr/bin/fish":  true,
+		"/tmp/x/bash":    false,
+		"fish":           false,
+		"/usr/bin/fish2": false,
+	} {
+		if got := shellAllowed(shell); got != want {
+			t.Errorf("shellAllowed(%q) = %v, want %v", shell, got, want)
+		}
+	}
+}
@@ -7,7 +7,8 @@ import (
 	"log"
 	"os"
 	"os/exec"
-	"strings"
+	"os/user"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -15,84 +16,58 @@ import (
 )
 
 // newTerminalImpl 创建一个新的终端实例。
-// 它会尝试根据用户配置文件查找默认 shell，如果失败则回退到常见 shell。
+// 未指定 shell 时按 UID 从 /etc/passwd 查找目标用户的默认 shell，失败则回退到常见 shell。
+// 指定了其他用户时，以该用户的身份启动 shell。
 // 优先以交互模式启动 shell，如果不支持则回退到非交互模式。
-func newTerminalImpl() (*terminalImpl, error) {
-	shell := ""
-	// 从 /etc/passwd 获取用户默认 shell
-	userHomeDir, err := os.UserHomeDir() // 获取当前用户的主目录
-	if err == nil {
-		passwdContent, err := os.ReadFile("/etc/passwd")
-		if err == nil {
-			for _, line := range strings.Split(string(passwdContent), "\n") {
-				if strings.Contains(line, userHomeDir) {
-					parts := strings.Split(line, ":")
-					if len(parts) >= 7 && parts[6] != "" {
-						shell = parts[6]
-						//log.Printf("Found shell from /etc/passwd: %s for user home: %s\n", shell, userHomeDir)
-						break
-					}
-				}
-			}
-		} else {
-			log.Printf("Error reading /etc/passwd: %v\n", err)
-		}
-	} else {
-		log.Printf("Error getting user home directory: %v\n", err)
+func newTerminalImpl(opts Options) (*terminalImpl, error) {
+	account, credential, err := resolveTerminalUser(opts.User)
+	if err != nil {
+		return nil, err
+	}
+	shell, err := resolveShell(opts.Shell, account)
+	if err != nil {
+		return nil, err
 	}
 
-	// 验证从 /etc/passwd 获取的 shell 是否可用
-	if shell != "" {
-		if _, err := exec.LookPath(shell); err != nil {
-			log.Printf("Shell '%s' from /etc/passwd not found in PATH, falling back.\n", shell)
-			shell = "" // 默认 shell 不可用，清空以进入回退逻辑
+	workingDir := opts.WorkingDir
+	if workingDir == "" && credential != nil {
+		workingDir = account.Home // 切换用户时从其主目录开始
+	}
+	if workingDir != "" {
+		if err := checkWorkingDir(workingDir); err != nil {
+			return nil, err
 		}
 	}
 
-	// 回退到默认 shell 列表
-	defaultShells := []string{"zsh", "bash", "sh"}
-	if shell == "" {
-		log.Println("Shell not found or invalid, trying default shells.")
-		for _, s := range defaultShells {
-			if _, err := exec.LookPath(s); err == nil {
-				shell = s
-				log.Printf("Using default shell: %s\n", shell)
-				break
-			}
-		}
+	env, err := mergeEnv(terminalBaseEnv(shell, account, credential != nil), opts.Env)
+	if err != nil {
+		return nil, err
 	}
 
-	if shell == "" {
-		return nil, fmt.Errorf("no supported shell found among %v", defaultShells)
+	cols, rows := opts.size()
+	start := func(cmd *exec.Cmd) (*os.File, error) {
+		cmd.Env = env
+		cmd.Dir = workingDir
+		if credential != nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{Credential: credential}
+		}
+		return pty.StartWithSize(cmd, &pty.Winsize{Rows: uint16(rows), Cols: uint16(cols)})
 	}
 
 	cmd := buildMotdShellCommand(shell)
-	cmd.Env = append(os.Environ(), // 继承系统环境变量
-		"TERM=xterm-256color", // 设置终端类型，提高兼容性
-		"LANG=C.UTF-8",        // 设置语言环境为 UTF-8
-		"LC_ALL=C.UTF-8",      // 强制所有本地化变量为 UTF-8
-	)
-
-	tty, err := pty.Start(cmd)
+	tty, err := start(cmd)
 	if err != nil {
 		// 回退到原始启动逻辑（直接启动 shell，再无参数）
 		cmd = exec.Command(shell)
-		cmd.Env = append(os.Environ(),
-			"TERM=xterm-256color",
-			"LANG=C.UTF-8",
-			"LC_ALL=C.UTF-8",
-		)
-		tty, err = pty.Start(cmd)
+		tty, err = start(cmd)
 		if err != nil {
 			return nil, fmt.Errorf("failed to start pty with argv0 prelude and plain shell: %v", err)
 		}
 	}
 
-	// 设置初始终端大小
-	pty.Setsize(tty, &pty.Winsize{Rows: 24, Cols: 80})
-
 	return &terminalImpl{
-		shell: shell,
+		shell:      shell,
+		workingDir: workingDir,
 		term: &unixTerminal{
 			tty: tty,
 			cmd: cmd,
@@ -100,6 +75,103 @@ func newTerminalImpl() (*terminalImpl, error) {
 	}, nil
 }
 
+// loginPath 切换用户时使用的 PATH
+const loginPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// terminalBaseEnv 构造 shell 的基础环境变量。
+// 以当前用户运行时继承 Agent 的环境；切换用户时只提供最小的登录环境，
+// 避免 AGENT_TOKEN 等 Agent 配置泄露给降权后的用户。
+func terminalBaseEnv(shell string, account *passwdEntry, switchUser bool) []string {
+	var base []string
+	if switchUser {
+		base = []string{"PATH=" + loginPath}
+	} else {
+		base = os.Environ()
+	}
+	base = append(base,
+		"TERM=xterm-256color", // 设置终端类型，提高兼容性
+		"LANG=C.UTF-8",        // 设置语言环境为 UTF-8
+		"LC_ALL=C.UTF-8",      // 强制所有本地化变量为 UTF-8
+		"SHELL="+shell,
+	)
+	if account != nil {
+		base = append(base, "HOME="+account.Home, "USER="+account.Name, "LOGNAME="+account.Name)
+	}
+	return base
+}
+
+// resolveTerminalUser 确定终端运行的用户。返回的 credential 非 nil 时需要降权到该用户
+func resolveTerminalUser(name string) (*passwdEntry, *syscall.Credential, error) {
+	uid := os.Getuid()
+	if name == "" {
+		account, err := lookupUserByUID(uid)
+		if err != nil {
+			log.Printf("Failed to look up current user: %v\n", err)
+			return nil, nil, nil
+		}
+		return account, nil, nil
+	}
+
+	account, err := lookupUserByName(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if account.UID == uid {
+		return account, nil, nil
+	}
+	if !userAllowed(account.Name) {
+		return nil, nil, fmt.Errorf("user %q is not allowed for terminal sessions", account.Name)
+	}
+	if os.Geteuid() != 0 {
+		return nil, nil, fmt.Errorf("switching to user %q requires the agent to run as root", account.Name)
+	}
+	credential := &syscall.Credential{Uid: uint32(account.UID), Gid: uint32(account.GID)}
+	if u, err := user.LookupId(strconv.Itoa(account.UID)); err == nil {
+		if ids, err := u.GroupIds(); err == nil {
+			for _, id := range ids {
+				if gid, err := strconv.ParseUint(id, 10, 32); err == nil {
+					credential.Groups = append(credential.Groups, uint32(gid))
+				}
+			}
+		}
+	}
+	return account, credential, nil
+}
+
+// resolveShell 确定要启动的 shell：优先使用请求中指定且被允许的 shell，
+// 其次是用户在 /etc/passwd 中的默认 shell，最后回退到常见 shell
+func resolveShell(requested string, account *passwdEntry) (string, error) {
+	if requested != "" {
+		if !shellAllowed(requested) {
+			return "", fmt.Errorf("shell %q is not allowed for terminal sessions", requested)
+		}
+		path, err := exec.LookPath(requested)
+		if err != nil {
+			return "", fmt.Errorf("shell %q not found: %v", requested, err)
+		}
+		return path, nil
+	}
+
+	// 验证从 /etc/passwd 获取的 shell 是否可用
+	if account != nil && account.Shell != "" {
+		if _, err := exec.LookPath(account.Shell); err == nil {
+			return account.Shell, nil
+		}
+		log.Printf("Shell '%s' from /etc/passwd not found in PATH, falling back.\n", account.Shell)
+	}
+
+	// 回退到默认 shell 列表
+	defaultShells := []string{"zsh", "bash", "sh"}
+	log.Println("Shell not found or invalid, trying default shells.")
+	for _, s := range defaultShells {
+		if _, err := exec.LookPath(s); err == nil {
+			log.Printf("Using default shell: %s\n", s)
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("no supported shell found among %v", defaultShells)
+}
+
 const motdShellPrelude = "for f in /etc/update-motd.d/*; do [ -e \"$f\" ] && [ -x \"$f\" ] && \"$f\"; done; [ -r /etc/motd ] && cat /etc/motd; exec \"$1\""
 
 func buildMotdShellCommand(shell string) *exec.Cmd {
@@ -39,3 +39,32 @@ func TestMotdShellPreludeIsPOSIXShSyntax(t *testing.T) {
 		t.Fatalf("MOTD prelude is not valid POSIX sh syntax: %v\n%s", err, output)
 	}
 }
+
+func TestTerminalBaseEnvDropsAgentEnvWhenSwitchingUser(t *testing.T) {
+	t.Setenv("AGENT_TOKEN", "secret")
+	account := &passwdEntry{Name: "alice", Home: "/home/alice"}
+
+	env, err := mergeEnv(terminalBaseEnv("/bin/bash", account, true), map[string]string{"EDITOR": "vim"})
+	if err != nil {
+		t.Fatalf("mergeEnv: %v", err)
+	}
+	want := []string{
+		"PATH=" + loginPath,
+		"TERM=xterm-256color",
+		"LANG=C.UTF-8",
+		"LC_ALL=C.UTF-8",
+		"SHELL=/bin/bash",
+		"HOME=/home/alice",
+		"USER=alice",
+		"LOGNAME=alice",
+		"EDITOR=vim",
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Fatalf("unexpected env:\n got %q\nwant %q", env, want)
+	}
+
+	inherited := strings.Join(terminalBaseEnv("/bin/bash", nil, false), "\n")
+	if !strings.Contains(inherited, "AGENT_TOKEN=secret") {
+		t.Fatal("expected environment to be inherited when running as the agent user")
+	}
+}
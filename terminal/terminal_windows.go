@@ -8,34 +8,63 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/UserExistsError/conpty"
 )
 
-func newTerminalImpl() (*terminalImpl, error) {
-	// 查找 shell
-	shell, err := exec.LookPath("powershell.exe")
-	if err != nil || shell == "" {
-		shell = "cmd.exe"
+func newTerminalImpl(opts Options) (*terminalImpl, error) {
+	if opts.User != "" {
+		return nil, fmt.Errorf("switching terminal user is not supported on Windows")
 	}
-	if shell == "" {
-		return nil, fmt.Errorf("no supported shell found")
+
+	// 查找 shell
+	var shell string
+	if opts.Shell != "" {
+		if !shellAllowed(opts.Shell) {
+			return nil, fmt.Errorf("shell %q is not allowed for terminal sessions", opts.Shell)
+		}
+		path, err := exec.LookPath(opts.Shell)
+		if err != nil {
+			return nil, fmt.Errorf("shell %q not found: %v", opts.Shell, err)
+		}
+		shell = path
+	} else {
+		path, err := exec.LookPath("powershell.exe")
+		if err != nil || path == "" {
+			path = "cmd.exe"
+		}
+		shell = path
 	}
 
 	// 获取工作目录
-	workingDir := "."
-	if executable, err := os.Executable(); err == nil {
-		workingDir = filepath.Dir(executable)
+	workingDir := opts.WorkingDir
+	if workingDir != "" {
+		if err := checkWorkingDir(workingDir); err != nil {
+			return nil, err
+		}
+	} else {
+		workingDir = "."
+		if executable, err := os.Executable(); err == nil {
+			workingDir = filepath.Dir(executable)
+		}
 	}
 
-	// 启动 ConPTY
-	tty, err := conpty.Start(shell, conpty.ConPtyWorkDir(workingDir))
+	env, err := mergeEnv(os.Environ(), opts.Env)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start conpty: %v", err)
+		return nil, err
 	}
 
-	// 设置初始终端大小
-	tty.Resize(80, 24)
+	// 启动 ConPTY，并设置初始终端大小
+	cols, rows := opts.size()
+	commandLine := shell
+	if strings.ContainsRune(shell, ' ') {
+		commandLine = `"` + shell + `"`
+	}
+	tty, err := conpty.Start(commandLine, conpty.ConPtyWorkDir(workingDir), conpty.ConPtyEnv(env), conpty.ConPtyDimensions(cols, rows))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start conpty: %v", err)
+	}
 
 	return &terminalImpl{
 		shell:      shell,